@@ -0,0 +1,151 @@
+package jmcomic
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// argError 某个参数有误，Error() 指出是哪一个参数，可以直接展示给用户
+type argError struct {
+	Args   []string // 出错时的全部参数，用于标出出错的位置
+	Index  int      // 出错参数在 Args 中的下标
+	Reason string
+}
+
+func (e *argError) Error() string {
+	if e.Index < 0 || e.Index >= len(e.Args) {
+		return e.Reason
+	}
+	marked := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		marked[i] = quoteArg(arg)
+	}
+	marked[e.Index] = "【" + marked[e.Index] + "】"
+	return fmt.Sprintf("参数 %s 有误: %s\n位置: %s", quoteArg(e.Args[e.Index]), e.Reason, strings.Join(marked, " "))
+}
+
+// quoteArg 需要时给参数加上引号，使错误提示中的参数与输入一致
+func quoteArg(arg string) string {
+	if arg == "" || strings.IndexFunc(arg, unicode.IsSpace) >= 0 {
+		return `"` + arg + `"`
+	}
+	return arg
+}
+
+// quotePairs 支持的引号，中文输入法常用的弯引号也可以使用
+var quotePairs = map[rune]rune{'"': '"', '\'': '\'', '“': '”', '‘': '’', '「': '」'}
+
+// splitArgs 按空白分割命令参数，引号中的内容 (可以包含空格) 作为一个参数
+// 双引号中可以用 \" 和 \\ 转义；引号没有闭合时返回指出该引号的错误
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		b       strings.Builder
+		inArg   bool // 当前参数已经开始 (空的引号也算一个参数)
+		closing rune // 当前所在引号的结束符，不在引号中时为0
+		openAt  int  // 当前引号的位置，用于错误提示
+	)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case closing != 0 && r == closing:
+			closing = 0
+		case closing == '"' && r == '\\' && i+size < len(s) && (s[i+size] == '"' || s[i+size] == '\\'):
+			b.WriteByte(s[i+size])
+			size++
+		case closing != 0:
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		case quotePairs[r] != 0:
+			closing, openAt, inArg = quotePairs[r], i, true
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+		i += size
+	}
+	if closing != 0 {
+		return nil, fmt.Errorf("引号没有闭合: %s", truncateRunes(s[openAt:], maxShortRunes))
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+// parseFlags 从参数中取出 specs 声明的选项，返回位置参数和选项值 (以完整选项名为键)
+// 支持 "--name value"、"--name=value"、"-n value" 和 "-n=value"；"--" 之后的参数都作为位置参数
+// 以 "--" 开头的未知选项视为错误，以 "-" 开头的其他参数 (如 "-1") 作为位置参数
+func parseFlags(args []string, specs []flagSpec) ([]string, map[string]string, error) {
+	rest := make([]string, 0, len(args))
+	values := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		var spec *flagSpec
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case strings.HasPrefix(name, "--") && len(name) > 2:
+			spec = findFlag(specs, strings.ToLower(name[2:]), false)
+			if spec == nil {
+				return nil, nil, &argError{Args: args, Index: i, Reason: "未知的选项" + flagsHint(specs)}
+			}
+		case strings.HasPrefix(name, "-") && len(name) == 2 && isASCIIAlnum(name[1]) && !isDigit(name[1]):
+			spec = findFlag(specs, strings.ToLower(name[1:]), true)
+			if spec == nil {
+				return nil, nil, &argError{Args: args, Index: i, Reason: "未知的选项" + flagsHint(specs)}
+			}
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if _, dup := values[spec.name]; dup {
+			return nil, nil, &argError{Args: args, Index: i, Reason: fmt.Sprintf("选项 --%s 重复指定", spec.name)}
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, &argError{Args: args, Index: i, Reason: fmt.Sprintf("选项 --%s 缺少值 (%s)", spec.name, spec.value)}
+			}
+			i++
+			value = args[i]
+		}
+		values[spec.name] = value
+	}
+	return rest, values, nil
+}
+
+// findFlag 按完整名称或单字母简写查找选项
+func findFlag(specs []flagSpec, name string, short bool) *flagSpec {
+	for i := range specs {
+		if (!short && specs[i].name == name) || (short && specs[i].short != "" && specs[i].short == name) {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+// flagsHint 列出可用的选项，例如 "，可用选项: --format (-f)"
+func flagsHint(specs []flagSpec) string {
+	if len(specs) == 0 {
+		return "，该命令没有选项"
+	}
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		name := "--" + spec.name
+		if spec.short != "" {
+			name += " (-" + spec.short + ")"
+		}
+		names = append(names, name)
+	}
+	return "，可用选项: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,103 @@
+package jmcomic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "   ", want: nil},
+		{in: "search 关键词", want: []string{"search", "关键词"}},
+		{in: "  a \t b\n c  ", want: []string{"a", "b", "c"}},
+		{in: `search "两个 词" --page 2`, want: []string{"search", "两个 词", "--page", "2"}},
+		{in: `a "" b`, want: []string{"a", "", "b"}},
+		{in: `pre"fix 后"缀`, want: []string{"prefix 后缀"}},
+		{in: `"a \"b\" \\ c"`, want: []string{`a "b" \ c`}},
+		{in: `'单 引号' “弯 引号” ‘单 弯’ 「直角 引号」`, want: []string{"单 引号", "弯 引号", "单 弯", "直角 引号"}},
+		{in: `'不转义 \'`, want: []string{`不转义 \`}},
+		{in: `a "未闭合`, wantErr: true},
+		{in: `“未闭合"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitArgs(%q) = %q，期望引号未闭合的错误", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v，期望 %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	specs := []flagSpec{
+		{name: "page", short: "p", value: "页码"},
+		{name: "sort", value: "排序"},
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		rest      []string
+		values    map[string]string
+		errIndex  int    // 期望出错的参数下标，-1 表示不出错
+		errReason string // 错误原因中应包含的文字
+	}{
+		{name: "没有选项", args: []string{"a", "b"}, rest: []string{"a", "b"}, values: map[string]string{}, errIndex: -1},
+		{name: "空格分隔", args: []string{"k", "--page", "2"}, rest: []string{"k"}, values: map[string]string{"page": "2"}, errIndex: -1},
+		{name: "等号", args: []string{"--page=3", "k"}, rest: []string{"k"}, values: map[string]string{"page": "3"}, errIndex: -1},
+		{name: "简写", args: []string{"-p", "4", "k"}, rest: []string{"k"}, values: map[string]string{"page": "4"}, errIndex: -1},
+		{name: "简写加等号", args: []string{"-p=5"}, rest: []string{}, values: map[string]string{"page": "5"}, errIndex: -1},
+		{name: "选项名不区分大小写", args: []string{"--SORT", "new"}, rest: []string{}, values: map[string]string{"sort": "new"}, errIndex: -1},
+		{name: "空值", args: []string{"--sort="}, rest: []string{}, values: map[string]string{"sort": ""}, errIndex: -1},
+		{name: "负数是位置参数", args: []string{"-1", "-23"}, rest: []string{"-1", "-23"}, values: map[string]string{}, errIndex: -1},
+		{name: "单独的短横线", args: []string{"-", "a-b"}, rest: []string{"-", "a-b"}, values: map[string]string{}, errIndex: -1},
+		{name: "双短横线之后", args: []string{"--", "--page", "2"}, rest: []string{"--page", "2"}, values: map[string]string{}, errIndex: -1},
+		{name: "未知选项", args: []string{"k", "--size", "1"}, errIndex: 1, errReason: "未知的选项"},
+		{name: "未知简写", args: []string{"-x"}, errIndex: 0, errReason: "未知的选项"},
+		{name: "缺少值", args: []string{"k", "--page"}, errIndex: 1, errReason: "缺少值"},
+		{name: "重复指定", args: []string{"-p", "1", "--page=2"}, errIndex: 2, errReason: "重复指定"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, values, err := parseFlags(tt.args, specs)
+			if tt.errIndex >= 0 {
+				var argErr *argError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("parseFlags(%q) 返回 %v，期望 *argError", tt.args, err)
+				}
+				if argErr.Index != tt.errIndex || !strings.Contains(argErr.Reason, tt.errReason) {
+					t.Fatalf("错误位置为 %d、原因为 %q，期望 %d、%q", argErr.Index, argErr.Reason, tt.errIndex, tt.errReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFlags(%q) 失败: %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(rest, tt.rest) || !reflect.DeepEqual(values, tt.values) {
+				t.Fatalf("parseFlags(%q) = %q, %v，期望 %q, %v", tt.args, rest, values, tt.rest, tt.values)
+			}
+		})
+	}
+}
+
+func TestArgErrorMessage(t *testing.T) {
+	err := &argError{Args: []string{"350234", "两个 词", "x"}, Index: 2, Reason: "无效"}
+	want := "参数 x 有误: 无效\n位置: 350234 \"两个 词\" 【x】"
+	if got := err.Error(); got != want {
+		t.Fatalf("Error() = %q，期望 %q", got, want)
+	}
+	if got := (&argError{Index: -1, Reason: "无效"}).Error(); got != "无效" {
+		t.Fatalf("没有位置时 Error() = %q", got)
+	}
+}
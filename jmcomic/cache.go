@@ -0,0 +1,341 @@
+package jmcomic
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+)
+
+// lruCache 带过期时间和容量上限的LRU缓存，并发安全
+// ttl <= 0 或 capacity <= 0 时缓存被禁用，get 总是未命中
+type lruCache[V any] struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	ll       *list.List // 队首为最近使用
+	items    map[string]*list.Element
+	dirty    bool             // 自上次持久化后是否有变化
+	now      func() time.Time // 当前时间，测试中可替换
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cacheEntry 缓存项，导出字段以便持久化
+type cacheEntry[V any] struct {
+	Key       string    `json:"key"`
+	Value     V         `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newLRUCache[V any](capacity int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{
+		ttl:      ttl,
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *lruCache[V]) enabled() bool {
+	return c.ttl > 0 && c.capacity > 0
+}
+
+// get 返回未过期的缓存值，并记录命中/未命中次数
+func (c *lruCache[V]) get(key string) (V, bool) {
+	var zero V
+	if !c.enabled() {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return zero, false
+	}
+	entry := el.Value.(*cacheEntry[V])
+	if c.now().After(entry.ExpiresAt) {
+		c.removeLocked(el)
+		c.misses.Add(1)
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits.Add(1)
+	return entry.Value, true
+}
+
+// put 写入缓存，超出容量时淘汰最久未使用的项
+func (c *lruCache[V]) put(key string, value V) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(&cacheEntry[V]{Key: key, Value: value, ExpiresAt: c.now().Add(c.ttl)})
+}
+
+func (c *lruCache[V]) putLocked(entry *cacheEntry[V]) {
+	if el, ok := c.items[entry.Key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[entry.Key] = c.ll.PushFront(entry)
+	}
+	for c.ll.Len() > c.capacity {
+		c.removeLocked(c.ll.Back())
+	}
+	c.dirty = true
+}
+
+func (c *lruCache[V]) removeLocked(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry[V]).Key)
+	c.dirty = true
+}
+
+// clear 清空缓存和统计，返回被清除的项数
+func (c *lruCache[V]) clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.ll.Len()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.dirty = true
+	return n
+}
+
+// snapshot 按从旧到新的顺序返回未过期的缓存项，恢复时依次写入即可保持LRU顺序
+func (c *lruCache[V]) snapshot() []cacheEntry[V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	entries := make([]cacheEntry[V], 0, c.ll.Len())
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*cacheEntry[V])
+		if now.Before(entry.ExpiresAt) {
+			entries = append(entries, *entry)
+		}
+	}
+	c.dirty = false
+	return entries
+}
+
+// restore 写入持久化的缓存项，跳过已过期的项
+func (c *lruCache[V]) restore(entries []cacheEntry[V]) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for i := range entries {
+		if now.Before(entries[i].ExpiresAt) {
+			c.putLocked(&entries[i])
+		}
+	}
+	c.dirty = false
+}
+
+func (c *lruCache[V]) isDirty() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dirty
+}
+
+// stats 返回缓存统计的描述，例如 "12/200 项, 命中 30, 未命中 10 (命中率 75.0%)"
+func (c *lruCache[V]) stats() string {
+	if !c.enabled() {
+		return "已禁用"
+	}
+	c.mu.Lock()
+	size := c.ll.Len()
+	c.mu.Unlock()
+	hits, misses := c.hits.Load(), c.misses.Load()
+	rate := 0.0
+	if hits+misses > 0 {
+		rate = float64(hits) / float64(hits+misses) * 100
+	}
+	return fmt.Sprintf("%d/%d 项, 命中 %d, 未命中 %d (命中率 %.1f%%)", size, c.capacity, hits, misses, rate)
+}
+
+var (
+	searchCache = newLRUCache[SearchPage](0, 0) // 在 initCaches 中按配置重建
+	detailCache = newLRUCache[ComicDetail](0, 0)
+)
+
+// searchCacheKey 由全部搜索条件组成缓存键
+func searchCacheKey(opts SearchOptions) string {
+	return strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(opts.Keyword)),
+		fmt.Sprint(opts.Page), opts.Sort, opts.Time, opts.Category, opts.MainTag,
+	}, "\x00")
+}
+
+// cachedAPI 为搜索和详情加上缓存，其余请求直接转发给内部的实现
+type cachedAPI struct {
+	API
+}
+
+// Search 按条件搜索漫画，优先使用缓存
+func (c cachedAPI) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	key := searchCacheKey(opts)
+	if cached, ok := searchCache.get(key); ok {
+		zlog.Debugf("[%s Cache] 搜索缓存命中: %s", pluginName, opts.Keyword)
+		return &cached, nil
+	}
+	result, err := c.API.Search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	searchCache.put(key, *result)
+	return result, nil
+}
+
+// Detail 获取漫画详情，优先使用缓存
+func (c cachedAPI) Detail(ctx context.Context, albumID string) (*ComicDetail, error) {
+	if cached, ok := detailCache.get(albumID); ok {
+		zlog.Debugf("[%s Cache] 详情缓存命中: %s", pluginName, albumID)
+		return &cached, nil
+	}
+	detail, err := c.API.Detail(ctx, albumID)
+	if err != nil {
+		return nil, err
+	}
+	detailCache.put(albumID, *detail)
+	return detail, nil
+}
+
+// persistedCaches 缓存文件的内容
+type persistedCaches struct {
+	Search []cacheEntry[SearchPage]  `json:"search"`
+	Detail []cacheEntry[ComicDetail] `json:"detail"`
+}
+
+// cachePersister 定期写回缓存的后台任务，stop 关闭后任务退出并关闭 done
+var cachePersister struct {
+	sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// initCaches 按配置创建缓存，开启持久化时从磁盘恢复并定期写回
+func initCaches() {
+	stopCachePersist()
+	searchCache = newLRUCache[SearchPage](cfg.SearchCacheSize, cfg.searchCacheTTL)
+	detailCache = newLRUCache[ComicDetail](cfg.DetailCacheSize, cfg.detailCacheTTL)
+	if !cfg.CachePersist {
+		return
+	}
+	if err := loadCaches(); err != nil {
+		zlog.Warnf("[%s Cache] 读取缓存文件失败，将从空缓存开始: %v", pluginName, err)
+	}
+	startCachePersist(cfg.cachePersistInterval)
+}
+
+// startCachePersist 启动定期写回缓存的后台任务
+func startCachePersist(interval time.Duration) {
+	cachePersister.Lock()
+	defer cachePersister.Unlock()
+	stop, done := make(chan struct{}), make(chan struct{})
+	cachePersister.stop, cachePersister.done = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if searchCache.isDirty() || detailCache.isDirty() {
+					if err := saveCaches(); err != nil {
+						zlog.Warnf("[%s Cache] 写入缓存文件失败: %v", pluginName, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// stopCachePersist 停止写回缓存的后台任务并等待其退出，没有运行时直接返回
+func stopCachePersist() {
+	cachePersister.Lock()
+	defer cachePersister.Unlock()
+	if cachePersister.stop == nil {
+		return
+	}
+	close(cachePersister.stop)
+	<-cachePersister.done
+	cachePersister.stop, cachePersister.done = nil, nil
+}
+
+// closeCaches 插件卸载时停止后台任务，开启持久化时最后写回一次
+func closeCaches() {
+	stopCachePersist()
+	if cfg.CachePersist {
+		if err := saveCaches(); err != nil {
+			zlog.Warnf("[%s] 卸载时写入缓存文件失败: %v", pluginName, err)
+		}
+	}
+}
+
+func loadCaches() error {
+	data, err := os.ReadFile(cfg.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var persisted persistedCaches
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return err
+	}
+	searchCache.restore(persisted.Search)
+	detailCache.restore(persisted.Detail)
+	zlog.Infof("[%s Cache] 已从 %s 恢复缓存: 搜索 %d 项, 详情 %d 项", pluginName, cfg.CacheFile, len(persisted.Search), len(persisted.Detail))
+	return nil
+}
+
+// saveCaches 将缓存写入磁盘，先写临时文件再重命名，避免中断时留下损坏的文件
+func saveCaches() error {
+	data, err := json.Marshal(persistedCaches{Search: searchCache.snapshot(), Detail: detailCache.snapshot()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.CacheFile), 0o755); err != nil {
+		return err
+	}
+	tmp := cfg.CacheFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cfg.CacheFile)
+}
+
+// clearCaches 清空所有缓存 (包括磁盘上的缓存文件)，返回被清除的项数
+func clearCaches() int {
+	n := searchCache.clear() + detailCache.clear()
+	if cfg.CachePersist {
+		if err := saveCaches(); err != nil {
+			zlog.Warnf("[%s Cache] 写入缓存文件失败: %v", pluginName, err)
+		}
+	}
+	return n
+}
@@ -0,0 +1,179 @@
+package jmcomic
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(capacity int, ttl time.Duration) (*lruCache[string], *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := newLRUCache[string](capacity, ttl)
+	c.now = clock.now
+	return c, clock
+}
+
+func cacheKeys(c *lruCache[string]) []string {
+	var keys []string
+	for _, e := range c.snapshot() {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	c, _ := newTestCache(3, time.Hour)
+	for _, k := range []string{"a", "b", "c"} {
+		c.put(k, k)
+	}
+	c.get("a") // a 变为最近使用，b 成为最久未使用
+	c.put("d", "d")
+	if _, ok := c.get("b"); ok {
+		t.Fatal("容量超出时应淘汰最久未使用的 b")
+	}
+	if got, want := cacheKeys(c), []string{"c", "a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("缓存顺序为 %v，期望 %v (从旧到新)", got, want)
+	}
+	c.put("c", "c2") // 更新已有的项不会淘汰其他项
+	if v, ok := c.get("c"); !ok || v != "c2" {
+		t.Fatalf("更新后 get(c) = %q, %v", v, ok)
+	}
+	if got := len(cacheKeys(c)); got != 3 {
+		t.Fatalf("缓存项数为 %d，期望 3", got)
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	c, clock := newTestCache(10, time.Minute)
+	c.put("a", "1")
+	clock.advance(30 * time.Second)
+	c.put("b", "2")
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("未过期的项应当命中")
+	}
+	clock.advance(31 * time.Second)
+	if _, ok := c.get("a"); ok {
+		t.Fatal("过期的项不应命中")
+	}
+	if _, ok := c.get("b"); !ok {
+		t.Fatal("b 还未过期")
+	}
+	if got := cacheKeys(c); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("过期的项应被移除，当前为 %v", got)
+	}
+	if c.hits.Load() != 2 || c.misses.Load() != 1 {
+		t.Fatalf("命中 %d 次、未命中 %d 次，期望 2 和 1", c.hits.Load(), c.misses.Load())
+	}
+}
+
+func TestLRUCacheDisabled(t *testing.T) {
+	for _, c := range []*lruCache[string]{newLRUCache[string](0, time.Hour), newLRUCache[string](10, 0)} {
+		c.put("a", "1")
+		if _, ok := c.get("a"); ok {
+			t.Fatal("禁用的缓存不应命中")
+		}
+		if c.stats() != "已禁用" {
+			t.Fatalf("stats() = %q", c.stats())
+		}
+	}
+}
+
+func TestLRUCacheRestore(t *testing.T) {
+	c, clock := newTestCache(10, time.Minute)
+	c.put("old", "1")
+	clock.advance(50 * time.Second)
+	c.put("new", "2")
+	entries := c.snapshot()
+	if c.isDirty() {
+		t.Fatal("snapshot 后不应为 dirty")
+	}
+
+	restored, clock2 := newTestCache(10, time.Minute)
+	clock2.t = clock.t.Add(20 * time.Second) // old 已过期，new 未过期
+	restored.restore(entries)
+	if got := cacheKeys(restored); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Fatalf("恢复后为 %v，期望只有 new", got)
+	}
+	if restored.isDirty() {
+		t.Fatal("restore 后不应为 dirty")
+	}
+}
+
+// withTestConfig 在测试结束后恢复全局配置，测试中可以直接修改 cfg
+func withTestConfig(t *testing.T) {
+	t.Helper()
+	saved := *cfg
+	t.Cleanup(func() { *cfg = saved })
+}
+
+// withCacheConfig 使用临时的缓存文件并在测试结束后恢复配置和缓存
+func withCacheConfig(t *testing.T) {
+	t.Helper()
+	withTestConfig(t)
+	oldSearch, oldDetail := searchCache, detailCache
+	t.Cleanup(func() {
+		stopCachePersist()
+		searchCache, detailCache = oldSearch, oldDetail
+	})
+	cfg.CachePersist = true
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache", "cache.json")
+	cfg.SearchCacheSize, cfg.searchCacheTTL = 10, time.Hour
+	cfg.DetailCacheSize, cfg.detailCacheTTL = 10, time.Hour
+	cfg.cachePersistInterval = time.Hour
+}
+
+func TestCachePersistence(t *testing.T) {
+	withCacheConfig(t)
+	initCaches()
+	detailCache.put("350234", ComicDetail{ID: "350234", Title: "标题"})
+	searchCache.put(searchCacheKey(SearchOptions{Keyword: "k", Page: 1}), SearchPage{Items: []ComicSearchResultItem{{ID: "1"}}})
+	closeCaches()
+
+	initCaches()
+	if d, ok := detailCache.get("350234"); !ok || d.Title != "标题" {
+		t.Fatalf("重新加载后详情缓存为 %+v, %v", d, ok)
+	}
+	if _, ok := searchCache.get(searchCacheKey(SearchOptions{Keyword: "K ", Page: 1})); !ok {
+		t.Fatal("重新加载后搜索缓存未命中")
+	}
+}
+
+func TestCachePersistLoop(t *testing.T) {
+	withCacheConfig(t)
+	cfg.cachePersistInterval = 10 * time.Millisecond
+	initCaches()
+	detailCache.put("1", ComicDetail{ID: "1"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(cfg.CacheFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("后台任务没有写回缓存文件")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stopCachePersist()
+	if cachePersister.stop != nil {
+		t.Fatal("停止后不应保留后台任务")
+	}
+	stopCachePersist() // 重复停止不应阻塞或 panic
+	if err := os.Remove(cfg.CacheFile); err != nil {
+		t.Fatal(err)
+	}
+	detailCache.put("2", ComicDetail{ID: "2"})
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(cfg.CacheFile); !os.IsNotExist(err) {
+		t.Fatal("后台任务停止后仍在写回缓存文件")
+	}
+}
@@ -0,0 +1,292 @@
+package jmcomic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+// selectorKind 章节选择的一种写法
+type selectorKind int
+
+const (
+	selectRange  selectorKind = iota // "3" 或 "1-5"，按序号
+	selectAll                        // "all"
+	selectLatest                     // "latest"，最后一个章节
+	selectLast                       // "last:3"，最后3个章节
+)
+
+// chapterSelector 一个章节选择，例如 "1-5"、"latest" 或 "last:3"
+type chapterSelector struct {
+	kind     selectorKind
+	from, to int // selectRange 的序号范围 (从1开始，包含两端)；selectLast 的个数保存在 from
+	arg      int // 所在参数的下标，用于错误提示
+}
+
+// chapterSelection 用户输入的章节选择，序号对应 jm detail 中显示的章节序号
+// 先解析 (不需要漫画详情)，获取详情后再用 resolve 得到具体章节
+type chapterSelection struct {
+	args      []string
+	selectors []chapterSelector
+}
+
+// selectorAliases 章节选择关键字的中文写法
+var selectorAliases = map[string]string{"全部": "all", "最新": "latest"}
+
+// parseChapterSelection 解析章节选择，每个参数可以是:
+// 序号 "3"、范围 "1-5"、逗号分隔的列表 "3,7,9" (可与范围混用)、"all" (全部)、"latest" (最新一章) 或 "last:3" (最后3章)
+// 格式有误时返回指出出错参数的 *argError
+func parseChapterSelection(args []string) (chapterSelection, error) {
+	sel := chapterSelection{args: args}
+	for i, arg := range args {
+		parts := strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == '，' || r == '、' })
+		if len(parts) == 0 {
+			return chapterSelection{}, &argError{Args: args, Index: i, Reason: "章节选择不能为空"}
+		}
+		for _, part := range parts {
+			s, err := parseChapterSelector(part)
+			if err != nil {
+				return chapterSelection{}, &argError{Args: args, Index: i, Reason: err.Error()}
+			}
+			s.arg = i
+			sel.selectors = append(sel.selectors, s)
+		}
+	}
+	return sel, nil
+}
+
+// parseChapterSelector 解析一个不含逗号的章节选择
+func parseChapterSelector(part string) (chapterSelector, error) {
+	lower := strings.ToLower(strings.TrimSpace(part))
+	if alias, ok := selectorAliases[lower]; ok {
+		lower = alias
+	}
+	switch {
+	case lower == "all":
+		return chapterSelector{kind: selectAll}, nil
+	case lower == "latest":
+		return chapterSelector{kind: selectLatest}, nil
+	case strings.HasPrefix(lower, "last:"):
+		n, err := strconv.Atoi(lower[len("last:"):])
+		if err != nil || n <= 0 {
+			return chapterSelector{}, fmt.Errorf("last: 后面应为正整数，例如 last:3")
+		}
+		return chapterSelector{kind: selectLast, from: n}, nil
+	}
+	fromText, toText, isRange := strings.Cut(lower, "-")
+	from, err := parseChapterNumber(fromText)
+	if err != nil {
+		return chapterSelector{}, err
+	}
+	to := from
+	if isRange {
+		if to, err = parseChapterNumber(toText); err != nil {
+			return chapterSelector{}, err
+		}
+		if to < from {
+			return chapterSelector{}, fmt.Errorf("范围 %d-%d 的起点大于终点", from, to)
+		}
+	}
+	return chapterSelector{kind: selectRange, from: from, to: to}, nil
+}
+
+func parseChapterNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无法识别的章节选择，应为序号 (如 3)、范围 (如 1-5)、列表 (如 3,7,9)、latest、last:3 或 all")
+	}
+	return n, nil
+}
+
+// resolve 按章节列表得到选中的章节，按章节顺序排列并去重
+// 章节按 orderedChapters 的顺序编号，与 jm detail 中显示的序号一致
+// 超出范围的单个数字如果是该漫画的章节ID则选中该章节，否则返回 *argError
+func (s chapterSelection) resolve(chapters []ChapterInfo) ([]ChapterInfo, error) {
+	ordered := orderedChapters(chapters)
+	total := len(ordered)
+	if total == 0 {
+		return nil, fmt.Errorf("该漫画没有可下载的章节")
+	}
+	selected := make([]bool, total)
+	for _, sel := range s.selectors {
+		from, to := sel.from, sel.to
+		switch sel.kind {
+		case selectAll:
+			from, to = 1, total
+		case selectLatest:
+			from, to = total, total
+		case selectLast:
+			from, to = total-sel.from+1, total
+			if from < 1 {
+				from = 1
+			}
+		default:
+			if to <= total {
+				break
+			}
+			// 超出序号范围的单个数字如果是该漫画的章节ID，选中该章节
+			if i := chapterPosition(ordered, strconv.Itoa(from)); from == to && i >= 0 {
+				from, to = i+1, i+1
+				break
+			}
+			return nil, &argError{Args: s.args, Index: sel.arg, Reason: fmt.Sprintf("章节序号超出范围，该漫画共 %d 个章节", total)}
+		}
+		for i := from; i <= to; i++ {
+			selected[i-1] = true
+		}
+	}
+	result := make([]ChapterInfo, 0, total)
+	for i, ok := range selected {
+		if ok {
+			result = append(result, ordered[i])
+		}
+	}
+	return result, nil
+}
+
+// orderedChapters 返回按章节顺序排列的副本: 所有章节的 Index 都是数字时按 Index 排序，否则保持API返回的顺序
+func orderedChapters(chapters []ChapterInfo) []ChapterInfo {
+	ordered := make([]ChapterInfo, len(chapters))
+	copy(ordered, chapters)
+	for _, chapter := range chapters {
+		if _, err := strconv.Atoi(chapter.Index); err != nil {
+			return ordered
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, _ := strconv.Atoi(ordered[i].Index)
+		b, _ := strconv.Atoi(ordered[j].Index)
+		return a < b
+	})
+	return ordered
+}
+
+// chapterPosition 返回章节ID在列表中的下标，找不到时返回 -1
+func chapterPosition(chapters []ChapterInfo, id string) int {
+	for i, chapter := range chapters {
+		if chapter.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// rawIDPrefix 强制按章节ID解析的前缀，例如 "id:350235"
+const rawIDPrefix = "id:"
+
+// chapterArgs jm download 中漫画ID后面的章节参数
+type chapterArgs struct {
+	ids       []sdk.PhotoID    // "id:" 前缀或章节链接给出的章节ID
+	selection chapterSelection // 其余参数，按序号选择
+}
+
+// empty 判断是否没有指定任何章节
+func (a chapterArgs) empty() bool {
+	return len(a.ids) == 0 && len(a.selection.selectors) == 0
+}
+
+// wholeAlbumArgs 选择全部章节，jm download 只给出漫画ID时使用
+func wholeAlbumArgs() chapterArgs {
+	return chapterArgs{selection: chapterSelection{selectors: []chapterSelector{{kind: selectAll}}}}
+}
+
+// wholeAlbum 判断是否选择了全部章节 (只给出漫画ID或包含 "all")
+func (a chapterArgs) wholeAlbum() bool {
+	if len(a.ids) > 0 {
+		return false
+	}
+	for _, sel := range a.selection.selectors {
+		if sel.kind == selectAll {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChapterArgs 解析下载命令的章节参数: "id:" 前缀和章节链接作为章节ID，其余作为章节选择 (见 parseChapterSelection)
+func parseChapterArgs(args []string) (chapterArgs, error) {
+	var result chapterArgs
+	var selectors []string
+	for i, arg := range args {
+		raw := arg
+		if len(arg) >= len(rawIDPrefix) && strings.EqualFold(arg[:len(rawIDPrefix)], rawIDPrefix) {
+			raw = arg[len(rawIDPrefix):]
+		} else if !looksLikeURL(strings.Trim(arg, refTrimChars)) {
+			selectors = append(selectors, arg)
+			continue
+		}
+		id, err := parseChapterID(raw)
+		if err != nil {
+			return chapterArgs{}, &argError{Args: args, Index: i, Reason: err.Error()}
+		}
+		result.ids = append(result.ids, id)
+	}
+	sel, err := parseChapterSelection(selectors)
+	if err != nil {
+		return chapterArgs{}, err
+	}
+	result.selection = sel
+	return result, nil
+}
+
+// resolve 按漫画的章节列表得到要下载的章节，按章节顺序排列并去重
+// 不属于该漫画的章节ID排在最后，只有ID没有标题
+func (a chapterArgs) resolve(chapters []ChapterInfo) ([]ChapterInfo, error) {
+	var selected []ChapterInfo
+	if len(a.selection.selectors) > 0 {
+		var err error
+		if selected, err = a.selection.resolve(chapters); err != nil {
+			return nil, err
+		}
+	}
+	ordered := orderedChapters(chapters)
+	picked := make([]bool, len(ordered))
+	for _, chapter := range selected {
+		picked[chapterPosition(ordered, chapter.ID)] = true
+	}
+	var extra []ChapterInfo
+	for _, id := range a.ids {
+		if i := chapterPosition(ordered, string(id)); i >= 0 {
+			picked[i] = true
+		} else if chapterPosition(extra, string(id)) < 0 {
+			extra = append(extra, ChapterInfo{ID: string(id)})
+		}
+	}
+	result := make([]ChapterInfo, 0, len(ordered)+len(extra))
+	for i, ok := range picked {
+		if ok {
+			result = append(result, ordered[i])
+		}
+	}
+	return append(result, extra...), nil
+}
+
+// estimatePages 按章节的 PageCount 估算总页数，页数未知的章节不计入
+func estimatePages(chapters []ChapterInfo) int {
+	total := 0
+	for _, chapter := range chapters {
+		total += chapter.PageCount
+	}
+	return total
+}
+
+// estimatePagesText 返回预计页数的描述，例如 "预计共 120 页"；部分章节页数未知时为 "预计至少 120 页"
+func estimatePagesText(chapters []ChapterInfo) string {
+	unknown := 0
+	for _, chapter := range chapters {
+		if chapter.PageCount <= 0 {
+			unknown++
+		}
+	}
+	switch {
+	case len(chapters) == 0 || unknown == len(chapters):
+		return "页数未知"
+	case unknown > 0:
+		return fmt.Sprintf("预计至少 %d 页", estimatePages(chapters))
+	default:
+		return fmt.Sprintf("预计共 %d 页", estimatePages(chapters))
+	}
+}
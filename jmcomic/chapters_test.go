@@ -0,0 +1,171 @@
+package jmcomic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+// testChapters 5个章节，API返回的顺序与序号不一致
+var testChapters = []ChapterInfo{
+	{ID: "103", Index: "3", PageCount: 30},
+	{ID: "101", Index: "1", PageCount: 10},
+	{ID: "102", Index: "2", PageCount: 20},
+	{ID: "105", Index: "5"},
+	{ID: "104", Index: "4", PageCount: 40},
+}
+
+func chapterIDs(chapters []ChapterInfo) []string {
+	ids := make([]string, 0, len(chapters))
+	for _, c := range chapters {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestChapterSelection(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     []string
+		errIndex int // 期望出错的参数下标，-1 表示不出错
+	}{
+		{args: []string{"1"}, want: []string{"101"}, errIndex: -1},
+		{args: []string{"2-4"}, want: []string{"102", "103", "104"}, errIndex: -1},
+		{args: []string{"4", "1-2"}, want: []string{"101", "102", "104"}, errIndex: -1},
+		{args: []string{"5,1,3"}, want: []string{"101", "103", "105"}, errIndex: -1},
+		{args: []string{"1-2，4、5"}, want: []string{"101", "102", "104", "105"}, errIndex: -1},
+		{args: []string{"1-3", "2-4"}, want: []string{"101", "102", "103", "104"}, errIndex: -1},
+		{args: []string{"3-3"}, want: []string{"103"}, errIndex: -1},
+		{args: []string{"all"}, want: []string{"101", "102", "103", "104", "105"}, errIndex: -1},
+		{args: []string{"全部"}, want: []string{"101", "102", "103", "104", "105"}, errIndex: -1},
+		{args: []string{"LATEST"}, want: []string{"105"}, errIndex: -1},
+		{args: []string{"最新", "1"}, want: []string{"101", "105"}, errIndex: -1},
+		{args: []string{"last:2"}, want: []string{"104", "105"}, errIndex: -1},
+		{args: []string{"last:10"}, want: []string{"101", "102", "103", "104", "105"}, errIndex: -1},
+		{args: []string{"104"}, want: []string{"104"}, errIndex: -1}, // 超出序号范围但是章节ID
+		{args: []string{"6"}, errIndex: 0},
+		{args: []string{"1", "4-6"}, errIndex: 1},
+		{args: []string{"999"}, errIndex: 0},
+		{args: []string{"0"}, errIndex: 0},
+		{args: []string{"-1"}, errIndex: 0},
+		{args: []string{"1", "3--5"}, errIndex: 1},
+		{args: []string{"5-2"}, errIndex: 0},
+		{args: []string{"last:0"}, errIndex: 0},
+		{args: []string{"last:-1"}, errIndex: 0},
+		{args: []string{"last:x"}, errIndex: 0},
+		{args: []string{"1", ","}, errIndex: 1},
+		{args: []string{"第一话"}, errIndex: 0},
+	}
+	for _, tt := range tests {
+		sel, err := parseChapterSelection(tt.args)
+		var got []ChapterInfo
+		if err == nil {
+			got, err = sel.resolve(testChapters)
+		}
+		if tt.errIndex >= 0 {
+			var argErr *argError
+			if !errors.As(err, &argErr) || argErr.Index != tt.errIndex {
+				t.Errorf("选择 %q 返回 %v，期望第 %d 个参数的 *argError", tt.args, err, tt.errIndex)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("选择 %q 失败: %v", tt.args, err)
+			continue
+		}
+		if ids := chapterIDs(got); !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("选择 %q 得到 %v，期望 %v", tt.args, ids, tt.want)
+		}
+	}
+}
+
+func TestChapterSelectionEmptyAlbum(t *testing.T) {
+	sel, err := parseChapterSelection([]string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sel.resolve(nil); err == nil {
+		t.Fatal("没有章节时应当失败")
+	}
+}
+
+func TestOrderedChapters(t *testing.T) {
+	if got := chapterIDs(orderedChapters(testChapters)); !reflect.DeepEqual(got, []string{"101", "102", "103", "104", "105"}) {
+		t.Fatalf("按序号排序为 %v", got)
+	}
+	// 有非数字的序号时保持API返回的顺序
+	mixed := []ChapterInfo{{ID: "2", Index: "2"}, {ID: "1", Index: "番外"}}
+	if got := chapterIDs(orderedChapters(mixed)); !reflect.DeepEqual(got, []string{"2", "1"}) {
+		t.Fatalf("序号不全是数字时为 %v", got)
+	}
+	if testChapters[0].ID != "103" {
+		t.Fatal("orderedChapters 不应修改原列表")
+	}
+}
+
+func TestChapterArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		ids   []sdk.PhotoID
+		want  []string
+		whole bool
+	}{
+		{args: nil, want: []string{}},
+		{args: []string{"all"}, want: []string{"101", "102", "103", "104", "105"}, whole: true},
+		{args: []string{"id:104", "1"}, ids: []sdk.PhotoID{"104"}, want: []string{"101", "104"}},
+		{args: []string{"ID:999", "id:999"}, ids: []sdk.PhotoID{"999", "999"}, want: []string{"999"}},
+		{args: []string{"https://18comic.vip/photo/102", "latest"}, ids: []sdk.PhotoID{"102"}, want: []string{"102", "105"}},
+		{args: []string{"id:103", "all"}, ids: []sdk.PhotoID{"103"}, want: []string{"101", "102", "103", "104", "105"}},
+	}
+	for _, tt := range tests {
+		a, err := parseChapterArgs(tt.args)
+		if err != nil {
+			t.Errorf("parseChapterArgs(%q) 失败: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(a.ids, tt.ids) || a.wholeAlbum() != tt.whole {
+			t.Errorf("parseChapterArgs(%q) 的章节ID为 %v、整本为 %v", tt.args, a.ids, a.wholeAlbum())
+		}
+		got, err := a.resolve(testChapters)
+		if err != nil {
+			t.Errorf("resolve(%q) 失败: %v", tt.args, err)
+			continue
+		}
+		if ids := chapterIDs(got); !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("resolve(%q) 得到 %v，期望 %v", tt.args, ids, tt.want)
+		}
+	}
+}
+
+func TestChapterArgsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"1", "id:abc"},
+		{"1", "https://18comic.vip/album/350234"},
+		{"1", "https://example.com/photo/1"},
+	} {
+		_, err := parseChapterArgs(args)
+		var argErr *argError
+		if !errors.As(err, &argErr) || argErr.Index != 1 {
+			t.Errorf("parseChapterArgs(%q) 返回 %v，期望第 1 个参数的 *argError", args, err)
+		}
+	}
+}
+
+func TestEstimatePagesText(t *testing.T) {
+	tests := []struct {
+		chapters []ChapterInfo
+		want     string
+	}{
+		{nil, "页数未知"},
+		{[]ChapterInfo{{ID: "1"}}, "页数未知"},
+		{testChapters[:3], "预计共 60 页"},
+		{testChapters, "预计至少 100 页"},
+	}
+	for _, tt := range tests {
+		if got := estimatePagesText(tt.chapters); got != tt.want {
+			t.Errorf("estimatePagesText(%v) = %q，期望 %q", chapterIDs(tt.chapters), got, tt.want)
+		}
+	}
+}
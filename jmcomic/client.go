@@ -0,0 +1,47 @@
+package jmcomic
+
+import (
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	zlog "github.com/FloatTech/zerobot/common/log"
+)
+
+// API 命令处理器依赖的接口，见 sdk.API
+type API = sdk.API
+
+var (
+	apiClient *sdk.Client // 插件自己创建的客户端，后端和客户端类型管理命令使用
+	api       API         // 命令处理器使用的接口，默认为带缓存和文本清理的 apiClient
+)
+
+// SetAPI 替换命令处理器使用的实现，例如在测试中使用假的API服务
+// 需在插件加载 (OnLoad) 之后调用，传入的实现不会经过插件的搜索和详情缓存，但返回的文本仍会被清理
+func SetAPI(a API) {
+	api = sanitizedAPI{a}
+}
+
+// sdkConfig 将插件配置转换为 sdk 客户端的配置
+func (c *PluginConfig) sdkConfig() sdk.Config {
+	return sdk.Config{
+		BaseURL:                 c.ApiBaseURL,
+		Backends:                c.ApiBackends,
+		BackendStrategy:         c.BackendStrategy,
+		HealthCheckInterval:     c.healthCheckInterval,
+		ClientType:              c.ApiClientType,
+		DisableClientFallback:   !c.ClientFallback,
+		ClientFallbackDecay:     c.clientFallbackDecay,
+		MaxRetries:              c.MaxRetries,
+		RetryBaseDelay:          c.retryBaseDelay,
+		RetryMaxDelay:           c.retryMaxDelay,
+		BreakerFailureThreshold: c.BreakerFailureThreshold,
+		BreakerCooldown:         c.breakerCooldown,
+		Logger:                  sdkLogger{},
+	}
+}
+
+// sdkLogger 将 sdk 的运行日志输出到插件日志
+type sdkLogger struct{}
+
+func (sdkLogger) Debugf(format string, args ...interface{}) { zlog.Debugf(format, args...) }
+func (sdkLogger) Infof(format string, args ...interface{})  { zlog.Infof(format, args...) }
+func (sdkLogger) Warnf(format string, args ...interface{})  { zlog.Warnf(format, args...) }
+func (sdkLogger) Errorf(format string, args ...interface{}) { zlog.Errorf(format, args...) }
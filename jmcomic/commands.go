@@ -0,0 +1,288 @@
+package jmcomic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// permission 执行子命令需要的权限
+type permission int
+
+const (
+	permEveryone  permission = iota // 所有人
+	permAdmin                       // 群管理员和超级用户
+	permSuperUser                   // 仅超级用户
+)
+
+// text 返回权限的中文描述，所有人可用时返回空字符串
+func (p permission) text() string {
+	switch p {
+	case permAdmin:
+		return "仅群管理员"
+	case permSuperUser:
+		return "仅超级用户"
+	default:
+		return ""
+	}
+}
+
+// allowed 判断当前用户是否有权限
+func (p permission) allowed(ctx *zero.Ctx) bool {
+	switch p {
+	case permAdmin:
+		return zero.SuperUserPermission(ctx) || zero.AdminPermission(ctx)
+	case permSuperUser:
+		return zero.SuperUserPermission(ctx)
+	default:
+		return true
+	}
+}
+
+// argSpec 子命令的一个位置参数
+type argSpec struct {
+	name     string // 用法中显示的名称，例如 "漫画ID"
+	optional bool
+	variadic bool // 可以重复多次，只能是最后一个参数
+}
+
+// flagSpec 子命令的一个 "--name value" 选项
+type flagSpec struct {
+	name  string
+	short string // 单字母简写，例如 "f" 表示 -f，可以为空
+	value string // 用法中显示的值，例如 "pdf|cbz|zip"
+}
+
+// command 一个子命令的声明，用法、帮助和参数检查都由这里生成
+type command struct {
+	name    string
+	aliases []string
+	summary string     // 帮助中的一句话说明
+	flags   []flagSpec // 选项，用法中显示在位置参数前面
+	args    []argSpec
+	notes   []string // 帮助中附加的说明行
+	details string   // 只在 jm help <命令> 中显示的详细说明
+	perm    permission
+	handler func(ctx *zero.Ctx, args []string)
+}
+
+// usage 返回命令的用法，例如 "preview <漫画ID> [章节] [页数]"
+func (c *command) usage() string {
+	parts := []string{c.name}
+	for _, f := range c.flags {
+		parts = append(parts, fmt.Sprintf("[--%s %s]", f.name, f.value))
+	}
+	for _, a := range c.args {
+		name := a.name
+		if a.variadic {
+			name += "..."
+		}
+		if a.optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// usageText 返回发送给用户的用法提示，例如 "格式: jm preview <漫画ID> [章节] [页数]"
+func (c *command) usageText() string {
+	return fmt.Sprintf("格式: %s %s", cmdPrefix, c.usage())
+}
+
+// checkArgs 检查选项和位置参数的个数，选项及其值不计入位置参数
+func (c *command) checkArgs(args []string) error {
+	rest, _, err := parseFlags(args, c.flags)
+	if err != nil {
+		return err
+	}
+	n := len(rest)
+	required, variadic := 0, false
+	for _, a := range c.args {
+		if !a.optional {
+			required++
+		}
+		variadic = variadic || a.variadic
+	}
+	if n < required {
+		return fmt.Errorf("参数不足！%s", c.usageText())
+	}
+	if !variadic && n > len(c.args) {
+		return fmt.Errorf("参数过多！%s", c.usageText())
+	}
+	return nil
+}
+
+// commandRegistry 按名称和别名查找子命令，并保存经过中间件包装的处理函数
+type commandRegistry struct {
+	list    []*command
+	byName  map[string]*command
+	handler commandFunc
+}
+
+// newCommandRegistry 注册子命令，名称或别名重复时 panic
+func newCommandRegistry(list []*command, middlewares ...middleware) *commandRegistry {
+	r := &commandRegistry{list: list, byName: make(map[string]*command)}
+	for _, c := range list {
+		for _, name := range append([]string{c.name}, c.aliases...) {
+			name = strings.ToLower(name)
+			if _, ok := r.byName[name]; ok {
+				panic(fmt.Sprintf("jmcomic: 子命令 %s 重复注册", name))
+			}
+			r.byName[name] = c
+		}
+	}
+	r.handler = chainMiddlewares(func(ctx *zero.Ctx, inv *invocation) {
+		inv.cmd.handler(ctx, inv.args)
+	}, middlewares...)
+	return r
+}
+
+// lookup 按名称或别名 (不区分大小写) 查找子命令
+func (r *commandRegistry) lookup(name string) (*command, bool) {
+	c, ok := r.byName[strings.ToLower(name)]
+	return c, ok
+}
+
+// run 经过中间件执行子命令
+func (r *commandRegistry) run(ctx *zero.Ctx, c *command, args []string) {
+	r.handler(ctx, &invocation{cmd: c, args: args})
+}
+
+// commands 插件的全部子命令，在 init 中创建，帮助按这里的顺序显示
+var commands *commandRegistry
+
+func init() {
+	commands = newCommandRegistry([]*command{
+		{
+			name: "help", aliases: []string{"帮助"}, summary: "显示帮助信息，指定命令时显示该命令的用法",
+			args:    []argSpec{{name: "命令", optional: true}},
+			handler: handleHelp,
+		},
+		{
+			name: "search", aliases: []string{"s", "搜索"}, summary: "搜索漫画",
+			flags:   searchFlags,
+			args:    []argSpec{{name: "关键词", variadic: true}},
+			details: searchUsage(),
+			handler: handleSearchComic,
+		},
+		{
+			name: "more", aliases: []string{"更多"}, summary: "查看上一次搜索的更多结果",
+			handler: func(ctx *zero.Ctx, _ []string) { handleSearchMore(ctx) },
+		},
+		{
+			name: "detail", aliases: []string{"info", "详情"}, summary: "获取漫画详情，漫画ID可以是 jm12345 或JM的漫画、章节链接",
+			args:    []argSpec{{name: "漫画ID"}},
+			handler: handleComicDetail,
+		},
+		{
+			name: "download", aliases: []string{"dl", "下载"}, summary: "下载指定章节，只给出漫画ID时下载整本，可打包为单个文件",
+			flags:   downloadFlags,
+			args:    []argSpec{{name: "漫画ID"}, {name: "章节", optional: true, variadic: true}},
+			notes:   []string{fmt.Sprintf("或直接: %s <漫画ID> <章节...> - 快速下载", cmdPrefix)},
+			details: "章节按详情中的序号选择，如 1 3 5-8、latest、last:3、all；章节ID需加 id: 前缀，如 id:350235\n不指定章节时下载整本漫画，页数较多时需要回复确认\n--format (-f): 下载完成后打包为单个 pdf、cbz 或 zip 文件",
+			handler: handleDownloadChapters,
+		},
+		{
+			name: "preview", aliases: []string{"预览"}, summary: "以合并转发预览章节前几页",
+			args:    []argSpec{{name: "漫画ID"}, {name: "章节", optional: true}, {name: "页数", optional: true}},
+			details: "章节与 download 的写法相同，如 2、latest 或 id:350235，只能选择一个章节；不指定时预览第一个章节",
+			handler: handlePreview,
+		},
+		{
+			name: "status", aliases: []string{"进度"}, summary: "查询下载任务状态",
+			args:    []argSpec{{name: "任务ID"}},
+			handler: handleJobStatus,
+		},
+		{
+			name: "jobs", aliases: []string{"任务"}, summary: "查看当前会话的下载任务",
+			handler: func(ctx *zero.Ctx, _ []string) { handleListJobs(ctx) },
+		},
+		{
+			name: "enable", aliases: []string{"启用"}, summary: "在本群启用插件",
+			perm:    permAdmin,
+			handler: func(ctx *zero.Ctx, _ []string) { setServiceEnabled(ctx, true) },
+		},
+		{
+			name: "disable", aliases: []string{"禁用"}, summary: "在本群禁用插件，包括自动识别",
+			perm:    permAdmin,
+			handler: func(ctx *zero.Ctx, _ []string) { setServiceEnabled(ctx, false) },
+		},
+		{
+			name: "cache", summary: "查看或清空缓存",
+			args:    []argSpec{{name: "stats|clear", optional: true}},
+			perm:    permSuperUser,
+			handler: handleCache,
+		},
+		{
+			name: "backend", summary: "查看API后端状态，停用或恢复后端",
+			args:    []argSpec{{name: "drain|undrain", optional: true}, {name: "序号|URL", optional: true}},
+			perm:    permSuperUser,
+			handler: handleBackend,
+		},
+		{
+			name: "admin", summary: "查看或设置JM客户端类型，查看命令统计",
+			args:    []argSpec{{name: "client|metrics"}, {name: "html|api|auto", optional: true}},
+			perm:    permSuperUser,
+			handler: handleAdmin,
+		},
+	},
+		metricsMiddleware,
+		loggingMiddleware,
+		recoverMiddleware,
+		authMiddleware,
+		rateLimitMiddleware,
+		argsMiddleware,
+	)
+}
+
+// sendUsage 回复子命令的用法，用于处理器中的参数格式错误
+func sendUsage(ctx *zero.Ctx, name string) {
+	if c, ok := commands.lookup(name); ok {
+		ctx.SendChain(message.Text(c.usageText()))
+	}
+}
+
+// commandHelp help 和 command_help 模板中的一个子命令
+type commandHelp struct {
+	Name       string
+	Usage      string // 不含命令前缀，例如 "detail <漫画ID>"
+	Summary    string
+	Aliases    []string
+	Permission string // 权限说明，所有人可用时为空
+	Notes      []string
+	Details    string // 详细说明，只在 command_help 模板中使用
+}
+
+func newCommandHelp(c *command) commandHelp {
+	return commandHelp{
+		Name: c.name, Usage: c.usage(), Summary: c.summary,
+		Aliases: c.aliases, Permission: c.perm.text(), Notes: c.notes, Details: c.details,
+	}
+}
+
+// handleHelp 显示帮助信息，args 中指定了子命令时只显示该命令的用法
+func handleHelp(ctx *zero.Ctx, args []string) {
+	if len(args) > 0 {
+		c, ok := commands.lookup(args[0])
+		if !ok {
+			ctx.SendChain(message.Text(fmt.Sprintf("没有名为 '%s' 的命令，发送 %s help 查看全部命令。", sanitizeText(args[0], maxShortRunes), cmdPrefix)))
+			return
+		}
+		ctx.SendChain(message.Text(renderText(tmplCommandHelp, newCommandHelp(c))))
+		return
+	}
+	ctx.SendChain(message.Text(renderText(tmplHelp, newHelpView())))
+}
+
+// newHelpView 按注册顺序列出全部子命令，也用作控制管理器中的插件帮助
+func newHelpView() helpView {
+	view := helpView{Name: strings.ToTitle(pluginName), Commands: make([]commandHelp, 0, len(commands.list))}
+	for _, c := range commands.list {
+		view.Commands = append(view.Commands, newCommandHelp(c))
+	}
+	return view
+}
@@ -1,15 +1,18 @@
 package jmcomic
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
 	"github.com/FloatTech/zerobot/common/config"
 	zlog "github.com/FloatTech/zerobot/common/log"
 )
 
 const (
-	pluginName   = "jmcomic" // 插件名称，用于配置和日志
-	pluginVersion = "1.2.0"   // 插件版本
+	pluginName    = "jmcomic"  // 插件名称，用于配置和日志
+	pluginVersion = "1.2.0"    // 插件版本
 	pluginAuthor  = "YourName" // 替换为你的名字
 	pluginDesc    = "通过API与JMComic交互，提供搜索、详情和下载请求功能。"
 )
@@ -21,18 +24,117 @@ type PluginConfig struct {
 	RequestTimeoutSeconds   int    `json:"request_timeout_seconds"`
 	MaxSearchResultsDisplay int    `json:"max_search_results_display"`
 	MaxChaptersDisplay      int    `json:"max_chapters_display"`
+	JobPollIntervalSeconds  int    `json:"job_poll_interval_seconds"` // 轮询下载任务状态的间隔
+	JobMaxWaitMinutes       int    `json:"job_max_wait_minutes"`      // 超过此时间仍未结束的任务将停止轮询
+	MaxJobsKept             int    `json:"max_jobs_kept"`             // 插件端最多保留的任务记录数
+	DeliverFiles            bool   `json:"deliver_files"`             // 下载完成后是否将文件发送到聊天
+	MaxUploadFileSizeMB     int    `json:"max_upload_file_size_mb"`   // 单个上传文件的大小上限，超过的文件会被跳过
+	DeliveryDir             string `json:"delivery_dir"`              // 上传前暂存文件的本地目录，需能被OneBot实现访问
+	PreviewPages            int    `json:"preview_pages"`             // jm preview 默认预览的页数
+	MaxPreviewPages         int    `json:"max_preview_pages"`         // jm preview 单次最多预览的页数
+	ShowCovers              bool   `json:"show_covers"`               // 搜索和详情结果中是否附带封面图
+	CoverCacheDir           string `json:"cover_cache_dir"`           // 封面图本地缓存目录
+	// GroupCoverOverrides 按群覆盖 ShowCovers，键为群号，例如 {"123456": false}
+	GroupCoverOverrides     map[string]bool `json:"group_cover_overrides"`
+	InteractiveSelection    bool            `json:"interactive_selection"`          // 搜索后是否允许回复序号选择结果
+	SelectionTimeoutSeconds int             `json:"selection_timeout_seconds"`      // 交互式选择会话的超时时间
+	SearchCacheTTLSeconds   int             `json:"search_cache_ttl_seconds"`       // 搜索结果缓存时间，0 表示不缓存
+	SearchCacheSize         int             `json:"search_cache_size"`              // 搜索结果最多缓存的条数
+	DetailCacheTTLSeconds   int             `json:"detail_cache_ttl_seconds"`       // 漫画详情缓存时间，0 表示不缓存
+	DetailCacheSize         int             `json:"detail_cache_size"`              // 漫画详情最多缓存的条数
+	CachePersist            bool            `json:"cache_persist"`                  // 是否将缓存保存到磁盘，重启后恢复
+	CacheFile               string          `json:"cache_file"`                     // 缓存文件路径
+	CachePersistIntervalSec int             `json:"cache_persist_interval_seconds"` // 缓存写回磁盘的间隔
+	MaxRetries              int             `json:"max_retries"`                    // GET请求失败后的最大重试次数，0 表示不重试
+	RetryBaseDelayMs        int             `json:"retry_base_delay_ms"`            // 首次重试前的基础等待时间
+	RetryMaxDelayMs         int             `json:"retry_max_delay_ms"`             // 重试等待时间上限
+	BreakerFailureThreshold int             `json:"breaker_failure_threshold"`      // 连续失败多少次后熔断，0 表示不熔断
+	BreakerCooldownSeconds  int             `json:"breaker_cooldown_seconds"`       // 熔断后多久探测一次 /health
+	// ApiBackends 多个API后端，配置后取代 ApiBaseURL，例如 [{"url": "http://10.0.0.2:5000", "weight": 2}]
+	ApiBackends                []sdk.Backend `json:"api_backends"`
+	BackendStrategy            string        `json:"backend_strategy"`              // 后端选择策略: round_robin 或 least_latency
+	HealthCheckIntervalSeconds int           `json:"health_check_interval_seconds"` // 后端健康检查的间隔
+	ClientFallback             bool          `json:"client_fallback"`               // 搜索和详情失败时是否自动改用另一种客户端类型
+	ClientFallbackDecaySeconds int           `json:"client_fallback_decay_seconds"` // 改用另一种客户端后多久重新尝试配置的类型
+	// JMDomains 识别链接时接受的JM域名 (含子域名)，包含 18comic、jmcomic 的镜像域名总是被接受
+	JMDomains []string `json:"jm_domains"`
+	// AutoRecognizeGroups 开启自动识别的群号，群聊中出现 "jm350234" 这样的ID时直接回复漫画信息
+	AutoRecognizeGroups          []int64  `json:"auto_recognize_groups"`
+	AutoRecognizeCooldownSeconds int      `json:"auto_recognize_cooldown_seconds"` // 同一个群两次自动回复的最小间隔
+	AutoRecognizeRequirePrefix   bool     `json:"auto_recognize_require_prefix"`   // 是否只识别带 jm 前缀的ID，关闭后也识别单独的数字
+	AutoRecognizeMinDigits       int      `json:"auto_recognize_min_digits"`       // 识别的ID最少位数
+	AutoRecognizeMaxDigits       int      `json:"auto_recognize_max_digits"`       // 识别的ID最多位数，可排除手机号等长数字
+	AutoRecognizeIgnorePatterns  []string `json:"auto_recognize_ignore_patterns"`  // 匹配这些正则的数字不会被识别，例如日期
+	TemplateDir                  string   `json:"template_dir"`                    // 覆盖消息模板的目录，文件名为 <模板名>.tmpl
+	CommandRateLimit             int      `json:"command_rate_limit"`              // 每个用户在一个时间窗口内最多执行的命令数，0 表示不限制
+	CommandRateWindowSeconds     int      `json:"command_rate_window_seconds"`     // 命令频率限制的时间窗口
+	DownloadConfirmPages         int      `json:"download_confirm_pages"`          // 下载整本漫画预计超过此页数时需要回复确认，0 表示不确认
 	// CommandPrefix string `json:"command_prefix"` // 如果不再需要可配置前缀，可以移除
 
 	// 内部使用
-	timeoutDuration time.Duration
+	timeoutDuration      time.Duration
+	jobPollInterval      time.Duration
+	jobMaxWait           time.Duration
+	maxUploadBytes       int64
+	selectionTimeout     time.Duration
+	searchCacheTTL       time.Duration
+	detailCacheTTL       time.Duration
+	cachePersistInterval time.Duration
+	retryBaseDelay       time.Duration
+	retryMaxDelay        time.Duration
+	breakerCooldown      time.Duration
+	healthCheckInterval  time.Duration
+	clientFallbackDecay  time.Duration
+	recognizeGroups      map[int64]bool
+	recognizeCooldown    time.Duration
+	recognizeIgnore      []*regexp.Regexp
+	commandRateWindow    time.Duration
 }
 
 var cfg = &PluginConfig{ // 默认配置
-	ApiBaseURL:              "http://localhost:5000",
-	ApiClientType:           "html",
-	RequestTimeoutSeconds:   30,
-	MaxSearchResultsDisplay: 5,
-	MaxChaptersDisplay:      10,
+	ApiBaseURL:                   "http://localhost:5000",
+	ApiClientType:                "html",
+	RequestTimeoutSeconds:        30,
+	MaxSearchResultsDisplay:      5,
+	MaxChaptersDisplay:           10,
+	JobPollIntervalSeconds:       5,
+	JobMaxWaitMinutes:            60,
+	MaxJobsKept:                  50,
+	DeliverFiles:                 true,
+	MaxUploadFileSizeMB:          30,
+	DeliveryDir:                  "data/jmcomic/deliveries",
+	PreviewPages:                 3,
+	MaxPreviewPages:              10,
+	ShowCovers:                   true,
+	InteractiveSelection:         true,
+	SelectionTimeoutSeconds:      60,
+	SearchCacheTTLSeconds:        600,
+	SearchCacheSize:              200,
+	DetailCacheTTLSeconds:        3600,
+	DetailCacheSize:              500,
+	CachePersist:                 true,
+	CacheFile:                    "data/jmcomic/cache.json",
+	CachePersistIntervalSec:      60,
+	MaxRetries:                   2,
+	RetryBaseDelayMs:             500,
+	RetryMaxDelayMs:              5000,
+	BreakerFailureThreshold:      5,
+	BreakerCooldownSeconds:       30,
+	BackendStrategy:              sdk.StrategyRoundRobin,
+	HealthCheckIntervalSeconds:   30,
+	ClientFallback:               true,
+	ClientFallbackDecaySeconds:   600,
+	CoverCacheDir:                "data/jmcomic/covers",
+	JMDomains:                    []string{"18comic.vip", "18comic.org", "jmcomic.me", "jmcomic1.me", "jm365.work", "jmcomic-zzz.one"},
+	AutoRecognizeCooldownSeconds: 60,
+	AutoRecognizeRequirePrefix:   true,
+	AutoRecognizeMinDigits:       3,
+	AutoRecognizeMaxDigits:       7,
+	AutoRecognizeIgnorePatterns:  []string{`^(19|20)\d{2}(0[1-9]|1[0-2])([0-2]\d|3[01])?$`, `^1[3-9]\d{9}$`},
+	TemplateDir:                  "data/jmcomic/templates",
+	CommandRateLimit:             20,
+	CommandRateWindowSeconds:     60,
+	DownloadConfirmPages:         500,
 	// CommandPrefix:           "jm",
 }
 
@@ -45,19 +147,150 @@ func init() {
 		zlog.Infof("[%s] 配置文件已加载", pluginName)
 	}
 
-	// 根据加载的配置更新内部使用的值
-	if cfg.RequestTimeoutSeconds <= 0 {
-		cfg.RequestTimeoutSeconds = 30
+	cfg.normalize()
+	// if cfg.CommandPrefix == "" { // 如果仍然保留CommandPrefix字段
+	// 	cfg.CommandPrefix = "jm"
+	// }
+}
+
+// normalize 修正无效的配置项并计算内部使用的值
+func (c *PluginConfig) normalize() {
+	if c.RequestTimeoutSeconds <= 0 {
+		c.RequestTimeoutSeconds = 30
 	}
-	cfg.timeoutDuration = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
+	c.timeoutDuration = time.Duration(c.RequestTimeoutSeconds) * time.Second
 
-	if cfg.MaxSearchResultsDisplay <= 0 {
-		cfg.MaxSearchResultsDisplay = 5
+	if c.MaxSearchResultsDisplay <= 0 {
+		c.MaxSearchResultsDisplay = 5
 	}
-	if cfg.MaxChaptersDisplay <= 0 {
-		cfg.MaxChaptersDisplay = 10
+	if c.MaxChaptersDisplay <= 0 {
+		c.MaxChaptersDisplay = 10
+	}
+	if c.JobPollIntervalSeconds <= 0 {
+		c.JobPollIntervalSeconds = 5
+	}
+	c.jobPollInterval = time.Duration(c.JobPollIntervalSeconds) * time.Second
+	if c.JobMaxWaitMinutes <= 0 {
+		c.JobMaxWaitMinutes = 60
+	}
+	c.jobMaxWait = time.Duration(c.JobMaxWaitMinutes) * time.Minute
+	if c.MaxJobsKept <= 0 {
+		c.MaxJobsKept = 50
+	}
+	if c.MaxUploadFileSizeMB <= 0 {
+		c.MaxUploadFileSizeMB = 30
+	}
+	c.maxUploadBytes = int64(c.MaxUploadFileSizeMB) * 1024 * 1024
+	if c.DeliveryDir == "" {
+		c.DeliveryDir = "data/jmcomic/deliveries"
+	}
+	if c.MaxPreviewPages <= 0 {
+		c.MaxPreviewPages = 10
+	}
+	if c.PreviewPages <= 0 {
+		c.PreviewPages = 3
+	}
+	if c.PreviewPages > c.MaxPreviewPages {
+		c.PreviewPages = c.MaxPreviewPages
+	}
+	if c.CoverCacheDir == "" {
+		c.CoverCacheDir = "data/jmcomic/covers"
+	}
+	if c.TemplateDir == "" {
+		c.TemplateDir = "data/jmcomic/templates"
+	}
+	if c.CommandRateLimit < 0 {
+		c.CommandRateLimit = 0
+	}
+	if c.CommandRateWindowSeconds <= 0 {
+		c.CommandRateWindowSeconds = 60
+	}
+	c.commandRateWindow = time.Duration(c.CommandRateWindowSeconds) * time.Second
+	if c.DownloadConfirmPages < 0 {
+		c.DownloadConfirmPages = 0
+	}
+	if c.SelectionTimeoutSeconds <= 0 {
+		c.SelectionTimeoutSeconds = 60
+	}
+	c.selectionTimeout = time.Duration(c.SelectionTimeoutSeconds) * time.Second
+	// 缓存时间和大小允许为0 (禁用缓存)，只修正负数
+	if c.SearchCacheTTLSeconds < 0 {
+		c.SearchCacheTTLSeconds = 0
+	}
+	c.searchCacheTTL = time.Duration(c.SearchCacheTTLSeconds) * time.Second
+	if c.DetailCacheTTLSeconds < 0 {
+		c.DetailCacheTTLSeconds = 0
+	}
+	c.detailCacheTTL = time.Duration(c.DetailCacheTTLSeconds) * time.Second
+	if c.SearchCacheSize < 0 {
+		c.SearchCacheSize = 0
+	}
+	if c.DetailCacheSize < 0 {
+		c.DetailCacheSize = 0
+	}
+	if c.CacheFile == "" {
+		c.CacheFile = "data/jmcomic/cache.json"
+	}
+	if c.CachePersistIntervalSec <= 0 {
+		c.CachePersistIntervalSec = 60
+	}
+	c.cachePersistInterval = time.Duration(c.CachePersistIntervalSec) * time.Second
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.RetryBaseDelayMs <= 0 {
+		c.RetryBaseDelayMs = 500
+	}
+	c.retryBaseDelay = time.Duration(c.RetryBaseDelayMs) * time.Millisecond
+	if c.RetryMaxDelayMs < c.RetryBaseDelayMs {
+		c.RetryMaxDelayMs = c.RetryBaseDelayMs
+	}
+	c.retryMaxDelay = time.Duration(c.RetryMaxDelayMs) * time.Millisecond
+	if c.BreakerFailureThreshold < 0 {
+		c.BreakerFailureThreshold = 0
+	}
+	if c.BreakerCooldownSeconds <= 0 {
+		c.BreakerCooldownSeconds = 30
+	}
+	c.breakerCooldown = time.Duration(c.BreakerCooldownSeconds) * time.Second
+	if c.BackendStrategy != sdk.StrategyLeastLatency {
+		c.BackendStrategy = sdk.StrategyRoundRobin
+	}
+	if c.HealthCheckIntervalSeconds <= 0 {
+		c.HealthCheckIntervalSeconds = 30
+	}
+	c.healthCheckInterval = time.Duration(c.HealthCheckIntervalSeconds) * time.Second
+	if c.ApiClientType != sdk.ClientAPI {
+		c.ApiClientType = sdk.ClientHTML
+	}
+	if c.ClientFallbackDecaySeconds <= 0 {
+		c.ClientFallbackDecaySeconds = 600
+	}
+	c.clientFallbackDecay = time.Duration(c.ClientFallbackDecaySeconds) * time.Second
+	for i, domain := range c.JMDomains {
+		c.JMDomains[i] = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+	}
+	c.recognizeGroups = make(map[int64]bool, len(c.AutoRecognizeGroups))
+	for _, groupID := range c.AutoRecognizeGroups {
+		c.recognizeGroups[groupID] = true
+	}
+	if c.AutoRecognizeCooldownSeconds < 0 {
+		c.AutoRecognizeCooldownSeconds = 0
+	}
+	c.recognizeCooldown = time.Duration(c.AutoRecognizeCooldownSeconds) * time.Second
+	if c.AutoRecognizeMinDigits <= 0 {
+		c.AutoRecognizeMinDigits = 3
+	}
+	if c.AutoRecognizeMaxDigits < c.AutoRecognizeMinDigits {
+		c.AutoRecognizeMaxDigits = c.AutoRecognizeMinDigits
+	}
+	c.recognizeIgnore = nil
+	for _, pattern := range c.AutoRecognizeIgnorePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			zlog.Warnf("[%s] 忽略无效的自动识别过滤规则 '%s': %v", pluginName, pattern, err)
+			continue
+		}
+		c.recognizeIgnore = append(c.recognizeIgnore, re)
 	}
-	// if cfg.CommandPrefix == "" { // 如果仍然保留CommandPrefix字段
-	// 	cfg.CommandPrefix = "jm"
-	// }
 }
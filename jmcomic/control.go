@@ -0,0 +1,106 @@
+package jmcomic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ctrl "github.com/FloatTech/zbpctrl"
+	"github.com/FloatTech/zbputils/control"
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// registerService 在控制管理器中注册插件，返回受管理的引擎
+// 管理器负责按群启用/禁用和黑名单，通过该引擎注册的命令和自动识别在禁用的群中都不会触发
+func registerService() *control.Engine {
+	return control.Register(pluginName, &ctrl.Options[*zero.Ctx]{
+		DisableOnDefault:  false,
+		Brief:             "JM漫画搜索与下载",
+		Help:              renderText(tmplHelp, newHelpView()),
+		PrivateDataFolder: pluginName,
+	})
+}
+
+// managedID 控制管理器中当前会话的ID: 群聊为群号，私聊为用户ID的相反数
+func managedID(ctx *zero.Ctx) int64 {
+	if ctx.Event.GroupID != 0 {
+		return ctx.Event.GroupID
+	}
+	return -ctx.Event.UserID
+}
+
+// serviceEnabled 判断插件在当前会话中是否启用，未在管理器中注册时视为启用
+// 交互式会话等不经过受管理引擎的回复需要自行检查
+func serviceEnabled(ctx *zero.Ctx) bool {
+	m, ok := control.Lookup(pluginName)
+	return !ok || m.IsEnabledIn(managedID(ctx))
+}
+
+// errServiceDisabled 插件在当前会话中已被禁用
+var errServiceDisabled = errors.New("插件已在当前会话中禁用")
+
+// notifyIfEnabled 发送任务通知、文件发送进度等命令结束后才发出的消息
+// 插件在会话中被禁用后不再发送，返回是否已发送
+func notifyIfEnabled(ctx *zero.Ctx, text string) bool {
+	if !serviceEnabled(ctx) {
+		return false
+	}
+	ctx.SendChain(message.Text(text))
+	return true
+}
+
+// setServiceEnabled 处理 jm enable / jm disable，在本群启用或禁用插件
+func setServiceEnabled(ctx *zero.Ctx, enable bool) {
+	groupID := ctx.Event.GroupID
+	if groupID == 0 {
+		ctx.SendChain(message.Text("该命令只能在群聊中使用。"))
+		return
+	}
+	m, ok := control.Lookup(pluginName)
+	if !ok {
+		ctx.SendChain(message.Text("插件未在控制管理器中注册，无法切换。"))
+		return
+	}
+	state := "禁用"
+	if enable {
+		state = "启用"
+	}
+	if m.IsEnabledIn(groupID) == enable {
+		ctx.SendChain(message.Text(fmt.Sprintf("本群已经%s了 %s。", state, pluginName)))
+		return
+	}
+	if enable {
+		m.Enable(groupID)
+	} else {
+		m.Disable(groupID)
+	}
+	zlog.Infof("[%s Control] 用户 %d 在群 %d %s了插件", pluginName, ctx.Event.UserID, groupID, state)
+	if enable {
+		ctx.SendChain(message.Text(fmt.Sprintf("已在本群启用 %s。", pluginName)))
+		return
+	}
+	ctx.SendChain(message.Text(fmt.Sprintf("已在本群禁用 %s，命令和自动识别都将不再响应。\n群管理员发送 %s enable 可重新启用。", pluginName, cmdPrefix)))
+}
+
+// reenableRule 插件在本群被禁用时，受管理的引擎不再处理任何命令
+// 只有 "jm enable" 由未受管理的引擎接收，使群管理员可以重新启用
+func reenableRule(ctx *zero.Ctx) bool {
+	if ctx.Event.GroupID == 0 || serviceEnabled(ctx) {
+		return false
+	}
+	fields := strings.Fields(ctx.Event.GetMessage().ExtractPlainText())
+	if len(fields) != 2 || !strings.EqualFold(fields[0], zero.BotConfig.CommandPrefix+cmdPrefix) {
+		return false
+	}
+	c, ok := commands.lookup(fields[1])
+	return ok && c.name == "enable"
+}
+
+// handleReenable 在禁用的群中执行 jm enable，经过与其他命令相同的中间件
+func handleReenable(ctx *zero.Ctx) {
+	if c, ok := commands.lookup("enable"); ok {
+		commands.run(ctx, c, nil)
+	}
+}
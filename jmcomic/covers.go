@@ -0,0 +1,107 @@
+package jmcomic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+)
+
+// coversEnabled 判断指定群是否显示封面，群配置优先于全局开关
+// groupID 为0 (私聊) 时使用全局开关
+func coversEnabled(groupID int64) bool {
+	if groupID != 0 {
+		if enabled, ok := cfg.GroupCoverOverrides[strconv.FormatInt(groupID, 10)]; ok {
+			return enabled
+		}
+	}
+	return cfg.ShowCovers
+}
+
+// getCover 返回漫画封面的本地缓存路径，缓存不存在时通过API服务下载
+func getCover(ctx context.Context, rawID string) (string, error) {
+	// 封面缓存文件以漫画ID命名，只接受校验过的ID
+	id, err := sdk.ParseAlbumID(rawID)
+	if err != nil {
+		return "", err
+	}
+	albumID := string(id)
+	dir, err := filepath.Abs(cfg.CoverCacheDir)
+	if err != nil {
+		return "", fmt.Errorf("解析封面缓存目录失败: %w", err)
+	}
+	path := filepath.Join(dir, albumID+".jpg")
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建封面缓存目录失败: %w", err)
+	}
+
+	// 先写入临时文件再重命名，避免并发请求或下载中断留下不完整的封面
+	tmp, err := os.CreateTemp(dir, albumID+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	_, err = api.Cover(ctx, albumID, tmp, cfg.maxUploadBytes)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// coverSegment 返回漫画封面的图片消息段，获取失败时返回 false，调用方只发送文字即可
+func coverSegment(ctx context.Context, albumID string) (message.Segment, bool) {
+	path, err := getCover(ctx, albumID)
+	if err != nil {
+		zlog.Warnf("[%s Covers] 获取漫画 %s 的封面失败: %v", pluginName, albumID, err)
+		return message.Segment{}, false
+	}
+	return message.Image(fileURI(path)), true
+}
+
+// fileURI 将本地绝对路径转换为OneBot可识别的 file:// URI
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if strings.HasPrefix(path, "/") {
+		return "file://" + path
+	}
+	return "file:///" + path // Windows 盘符路径
+}
+
+// coverSegments 并发获取多部漫画的封面，返回以漫画ID为键的图片消息段，获取失败的不包含在内
+func coverSegments(ctx context.Context, albumIDs []string) map[string]message.Segment {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		segments = make(map[string]message.Segment, len(albumIDs))
+	)
+	for _, id := range albumIDs {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if seg, ok := coverSegment(ctx, id); ok {
+				mu.Lock()
+				segments[id] = seg
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return segments
+}
@@ -0,0 +1,317 @@
+package jmcomic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/packager"
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	zlog "github.com/FloatTech/zerobot/common/log"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// deliverJobFiles 将已完成任务的文件从API服务取回到本地，再通过OneBot文件上传发送到原会话
+// 文件暂存在 DeliveryDir/<任务ID> 下，上传结束后删除
+// 插件在原会话中被禁用后不再取回和发送文件
+func deliverJobFiles(ctx *zero.Ctx, job DownloadJob) {
+	if !deliveryEnabled(ctx, job) {
+		return
+	}
+	listCtx, cancel := context.WithTimeout(sdk.WithBackend(context.Background(), job.Backend), cfg.timeoutDuration)
+	files, err := api.JobFiles(listCtx, job.JobID)
+	cancel()
+	if err != nil {
+		zlog.Errorf("[%s Delivery] 获取任务 %s 的文件列表失败: %v", pluginName, job.JobID, err)
+		notifyIfEnabled(ctx, fmt.Sprintf("获取任务 %s 的文件列表失败，无法发送文件。", job.JobID))
+		return
+	}
+	if len(files) == 0 {
+		notifyIfEnabled(ctx, fmt.Sprintf("任务 %s 没有可发送的文件。", job.JobID))
+		return
+	}
+
+	dir, err := filepath.Abs(filepath.Join(cfg.DeliveryDir, job.JobID))
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	if err != nil {
+		zlog.Errorf("[%s Delivery] 创建暂存目录失败: %v", pluginName, err)
+		notifyIfEnabled(ctx, "创建本地暂存目录失败，无法发送文件。")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if job.Format != "" {
+		deliverPackagedJob(ctx, job, files, dir)
+		return
+	}
+
+	notifyIfEnabled(ctx, fmt.Sprintf("正在发送任务 %s 的 %d 个文件...", job.JobID, len(files)))
+
+	sent := 0
+	var skipped []string
+	usedNames := make(map[string]bool)
+	for _, f := range files {
+		if !deliveryEnabled(ctx, job) {
+			return
+		}
+		if f.Size > cfg.maxUploadBytes {
+			skipped = append(skipped, fmt.Sprintf("%s (超过 %dMB)", f.Name, cfg.MaxUploadFileSizeMB))
+			continue
+		}
+		chapterID, name, err := checkJobFile(f)
+		if err != nil {
+			zlog.Warnf("[%s Delivery] 任务 %s 的文件信息无效: %v", pluginName, job.JobID, err)
+			skipped = append(skipped, fmt.Sprintf("%s (文件信息无效)", f.Name))
+			continue
+		}
+		uploadName := uniqueName(usedNames, "", fmt.Sprintf("%s_%s_%s", job.AlbumID, chapterID, name))
+		localPath := filepath.Join(dir, uploadName)
+		if !withinDir(dir, localPath) {
+			zlog.Warnf("[%s Delivery] 任务 %s 的文件 %s 不在暂存目录中，已跳过", pluginName, job.JobID, uploadName)
+			skipped = append(skipped, fmt.Sprintf("%s (文件信息无效)", f.Name))
+			continue
+		}
+		if _, err := fetchJobFileTo(job, f, localPath, cfg.maxUploadBytes); err != nil {
+			zlog.Errorf("[%s Delivery] 下载任务 %s 文件 %s 失败: %v", pluginName, job.JobID, f.Name, err)
+			skipped = append(skipped, fmt.Sprintf("%s (下载失败)", f.Name))
+			continue
+		}
+		err = uploadFile(ctx, localPath, uploadName)
+		os.Remove(localPath) // 逐个上传，暂存目录中最多只有一个文件
+		if err != nil {
+			zlog.Errorf("[%s Delivery] 上传文件 %s 失败: %v", pluginName, uploadName, err)
+			skipped = append(skipped, fmt.Sprintf("%s (上传失败)", f.Name))
+			continue
+		}
+		sent++
+	}
+
+	summary := fmt.Sprintf("任务 %s 文件发送完毕: 成功 %d 个", job.JobID, sent)
+	if len(skipped) > 0 {
+		summary += fmt.Sprintf("，跳过 %d 个", len(skipped))
+		shown := skipped
+		if len(shown) > maxJobsDisplay {
+			shown = shown[:maxJobsDisplay]
+		}
+		summary += ":\n" + strings.Join(shown, "\n")
+		if len(skipped) > len(shown) {
+			summary += "\n..."
+		}
+	}
+	notifyIfEnabled(ctx, escapeCQ(summary))
+}
+
+// deliverPackagedJob 取回任务的全部图片，按章节顺序打包为单个文件后上传
+func deliverPackagedJob(ctx *zero.Ctx, job DownloadJob, files []JobFile, dir string) {
+	format, err := packager.ParseFormat(job.Format)
+	if err != nil {
+		notifyIfEnabled(ctx, sanitizeBlock(err.Error(), 0))
+		return
+	}
+	// 打包后的文件超过上传限制时无法发送，图片总大小超过限制时不再取回
+	if total := packageSize(files); total > cfg.maxUploadBytes {
+		notifyIfEnabled(ctx, fmt.Sprintf("任务 %s 的图片共 %.1fMB，超过上传限制 %dMB，未打包发送。", job.JobID, float64(total)/1024/1024, cfg.MaxUploadFileSizeMB))
+		return
+	}
+	notifyIfEnabled(ctx, fmt.Sprintf("正在将任务 %s 的 %d 张图片打包为 %s...", job.JobID, len(files), strings.ToUpper(string(format))))
+
+	// 章节标题和序号以漫画详情为准，获取失败时退化为API返回的章节序号
+	meta := packager.Metadata{ID: job.AlbumID, Title: "JM" + job.AlbumID}
+	chapters := make(map[string]ChapterInfo)
+	detailCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	detail, err := api.Detail(detailCtx, job.AlbumID)
+	cancel()
+	if err != nil {
+		zlog.Warnf("[%s Delivery] 获取漫画 %s 详情失败，打包时将缺少元数据: %v", pluginName, job.AlbumID, err)
+	} else {
+		meta = packager.Metadata{
+			ID:      detail.ID,
+			Title:   detail.Title,
+			Writer:  detail.Author,
+			Tags:    detail.Tags,
+			Summary: detail.Description,
+		}
+		for _, chapter := range detail.Chapters {
+			chapters[chapter.ID] = chapter
+		}
+	}
+
+	pages := make([]packager.Page, 0, len(files))
+	usedNames := make(map[string]bool)
+	var staged int64 // 已取回的字节数，API返回的文件大小不可信，取回时按剩余额度限制
+	for _, f := range files {
+		if !deliveryEnabled(ctx, job) {
+			return
+		}
+		chapterID, name, err := checkJobFile(f)
+		if err != nil {
+			zlog.Errorf("[%s Delivery] 任务 %s 的文件信息无效: %v", pluginName, job.JobID, err)
+			notifyIfEnabled(ctx, fmt.Sprintf("任务 %s 的文件信息无效，无法打包。", job.JobID))
+			return
+		}
+		name = uniqueName(usedNames, string(chapterID), name)
+		chapterDir := filepath.Join(dir, string(chapterID))
+		localPath := filepath.Join(chapterDir, name)
+		if !withinDir(dir, localPath) {
+			zlog.Errorf("[%s Delivery] 任务 %s 的文件 %s 不在暂存目录中", pluginName, job.JobID, localPath)
+			notifyIfEnabled(ctx, fmt.Sprintf("任务 %s 的文件信息无效，无法打包。", job.JobID))
+			return
+		}
+		if err := os.MkdirAll(chapterDir, 0o755); err != nil {
+			zlog.Errorf("[%s Delivery] 创建章节目录失败: %v", pluginName, err)
+			notifyIfEnabled(ctx, "创建本地暂存目录失败，无法打包。")
+			return
+		}
+		n, err := fetchJobFileTo(job, f, localPath, cfg.maxUploadBytes-staged)
+		if err != nil {
+			zlog.Errorf("[%s Delivery] 下载任务 %s 文件 %s 失败: %v", pluginName, job.JobID, f.Name, err)
+			notifyIfEnabled(ctx, fmt.Sprintf("下载图片 %s 失败，无法打包任务 %s。", sanitizeText(f.Name, maxNameRunes), job.JobID))
+			return
+		}
+		staged += n
+		page := packager.Page{ChapterID: string(chapterID), ChapterIndex: f.ChapterIndex, Name: name, Path: localPath}
+		if chapter, ok := chapters[string(chapterID)]; ok {
+			page.ChapterIndex = chapter.Index
+			page.ChapterTitle = chapter.Title
+		}
+		pages = append(pages, page)
+	}
+
+	archiveName := fmt.Sprintf("JM%s%s", job.AlbumID, format.Ext())
+	archivePath := filepath.Join(dir, archiveName)
+	if err := packager.PackFile(format, meta, pages, archivePath); err != nil {
+		zlog.Errorf("[%s Delivery] 打包任务 %s 失败: %v", pluginName, job.JobID, err)
+		notifyIfEnabled(ctx, fmt.Sprintf("打包任务 %s 失败。", job.JobID))
+		return
+	}
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		zlog.Errorf("[%s Delivery] 读取打包文件信息失败: %v", pluginName, err)
+		notifyIfEnabled(ctx, fmt.Sprintf("打包任务 %s 失败。", job.JobID))
+		return
+	}
+	if info.Size() > cfg.maxUploadBytes {
+		notifyIfEnabled(ctx, fmt.Sprintf("打包后的文件 %s 大小为 %.1fMB，超过上传限制 %dMB，未发送。", archiveName, float64(info.Size())/1024/1024, cfg.MaxUploadFileSizeMB))
+		return
+	}
+	if err := uploadFile(ctx, archivePath, archiveName); err != nil {
+		zlog.Errorf("[%s Delivery] 上传文件 %s 失败: %v", pluginName, archiveName, err)
+		notifyIfEnabled(ctx, fmt.Sprintf("上传文件 %s 失败。", archiveName))
+		return
+	}
+	zlog.Infof("[%s Delivery] 任务 %s 已打包为 %s 并发送 (%d 页)", pluginName, job.JobID, archiveName, len(pages))
+}
+
+// checkJobFile 检查API返回的章节ID、文件名和大小，章节ID和文件名会用作本地路径的一部分
+// 章节ID必须是数字，文件名只保留最后一段，不能为空、"." 或 ".."；大小不能超过上传限制
+func checkJobFile(f JobFile) (sdk.PhotoID, string, error) {
+	chapterID, err := sdk.ParsePhotoID(f.ChapterID)
+	if err != nil {
+		return "", "", fmt.Errorf("文件 '%s' 的章节ID无效: %w", f.Name, err)
+	}
+	if f.Size < 0 || f.Size > cfg.maxUploadBytes {
+		return "", "", fmt.Errorf("文件 '%s' 的大小 %d 字节无效或超过上传限制", f.Name, f.Size)
+	}
+	name := filepath.Base(f.Name)
+	if name == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", "", fmt.Errorf("无效的文件名 '%s'", f.Name)
+	}
+	return chapterID, name, nil
+}
+
+// packageSize 任务图片的总字节数，大小无效的文件由 checkJobFile 处理
+func packageSize(files []JobFile) int64 {
+	var total int64
+	for _, f := range files {
+		if f.Size > math.MaxInt64-total { // 防止异常的大小溢出
+			return math.MaxInt64
+		}
+		if f.Size > 0 {
+			total += f.Size
+		}
+	}
+	return total
+}
+
+// uniqueName 同一目录 (scope) 中文件名重复时在扩展名前加序号，避免后取回的文件覆盖之前的
+// 按不区分大小写比较，Windows 上的文件名不区分大小写
+func uniqueName(used map[string]bool, scope, name string) string {
+	ext := filepath.Ext(name)
+	unique := name
+	for i := 2; used[scope+"/"+strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	used[scope+"/"+strings.ToLower(unique)] = true
+	return unique
+}
+
+// withinDir 判断 path 是否位于 dir 之内 (两者都应为清理过的绝对路径)
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// fetchJobFileTo 下载任务中的单个文件到本地路径，返回写入的字节数
+// 文件超过 maxBytes 或下载失败时删除不完整的文件
+func fetchJobFileTo(job DownloadJob, f JobFile, localPath string, maxBytes int64) (int64, error) {
+	if maxBytes <= 0 { // FetchJobFile 的 maxBytes <= 0 表示不限制
+		return 0, fmt.Errorf("文件 '%s' 超过剩余的大小额度", f.Name)
+	}
+	out, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("创建本地文件失败: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(sdk.WithBackend(context.Background(), job.Backend), cfg.timeoutDuration)
+	defer cancel()
+	n, err := api.FetchJobFile(reqCtx, job.JobID, f.Index, out, maxBytes)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return 0, err
+	}
+	return n, nil
+}
+
+// deliveryEnabled 检查插件是否仍在原会话中启用，禁用后停止发送文件
+func deliveryEnabled(ctx *zero.Ctx, job DownloadJob) bool {
+	if serviceEnabled(ctx) {
+		return true
+	}
+	zlog.Infof("[%s Delivery] 插件已在会话 %d 中禁用，停止发送任务 %s 的文件", pluginName, managedID(ctx), job.JobID)
+	return false
+}
+
+// uploadFile 通过OneBot将本地文件上传到当前会话 (群文件或私聊文件)
+func uploadFile(ctx *zero.Ctx, localPath, name string) error {
+	if !serviceEnabled(ctx) {
+		return errServiceDisabled
+	}
+	var resp zero.APIResponse
+	if ctx.Event.GroupID != 0 {
+		resp = ctx.CallAction("upload_group_file", zero.Params{
+			"group_id": ctx.Event.GroupID,
+			"file":     localPath,
+			"name":     name,
+		})
+	} else {
+		resp = ctx.CallAction("upload_private_file", zero.Params{
+			"user_id": ctx.Event.UserID,
+			"file":    localPath,
+			"name":    name,
+		})
+	}
+	if resp.RetCode != 0 {
+		return fmt.Errorf("OneBot返回错误 (retcode %d): %s", resp.RetCode, resp.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package jmcomic
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+func TestCheckJobFile(t *testing.T) {
+	withTestConfig(t)
+	cfg.maxUploadBytes = 1024
+	tests := []struct {
+		file    JobFile
+		chapter sdk.PhotoID
+		name    string
+		wantErr bool
+	}{
+		{file: JobFile{ChapterID: "422866", Name: "00001.jpg", Size: 100}, chapter: "422866", name: "00001.jpg"},
+		{file: JobFile{ChapterID: "422866", Name: "../../etc/passwd"}, chapter: "422866", name: "passwd"},
+		{file: JobFile{ChapterID: "422866", Name: "/etc/passwd"}, chapter: "422866", name: "passwd"},
+		{file: JobFile{ChapterID: "422866", Name: "a/b/00002.webp"}, chapter: "422866", name: "00002.webp"},
+		{file: JobFile{ChapterID: "422866", Name: "00003.jpg", Size: 1024}, chapter: "422866", name: "00003.jpg"},
+		{file: JobFile{ChapterID: "422866", Name: ""}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: "."}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: ".."}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: "a/.."}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: "/"}, wantErr: true},
+		{file: JobFile{ChapterID: "../422866", Name: "00001.jpg"}, wantErr: true},
+		{file: JobFile{ChapterID: "", Name: "00001.jpg"}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: "big.jpg", Size: 1025}, wantErr: true},
+		{file: JobFile{ChapterID: "422866", Name: "neg.jpg", Size: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		chapter, name, err := checkJobFile(tt.file)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("checkJobFile(%+v) = %q, %q，期望失败", tt.file, chapter, name)
+			}
+			continue
+		}
+		if err != nil || chapter != tt.chapter || name != tt.name {
+			t.Errorf("checkJobFile(%+v) = %q, %q, %v，期望 %q, %q", tt.file, chapter, name, err, tt.chapter, tt.name)
+		}
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator), "data", "deliveries", "job1")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "a.jpg"), true},
+		{filepath.Join(dir, "422866", "a.jpg"), true},
+		{filepath.Join(dir, "..a.jpg"), true},
+		{dir, false},
+		{filepath.Join(dir, ".."), false},
+		{filepath.Join(dir, "..", "job2", "a.jpg"), false},
+		{filepath.Join(dir, "..", "..", "..", "etc", "passwd"), false},
+		{dir + "2", false},
+		{filepath.Join(string(filepath.Separator), "etc", "passwd"), false},
+	}
+	for _, tt := range tests {
+		if got := withinDir(dir, tt.path); got != tt.want {
+			t.Errorf("withinDir(%q, %q) = %v，期望 %v", dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	used := make(map[string]bool)
+	tests := []struct{ scope, name, want string }{
+		{"1", "001.jpg", "001.jpg"},
+		{"1", "001.jpg", "001_2.jpg"},
+		{"1", "001.JPG", "001_3.JPG"}, // 不区分大小写
+		{"2", "001.jpg", "001.jpg"},   // 不同章节目录
+		{"1", "001_2.jpg", "001_2_2.jpg"},
+		{"1", "noext", "noext"},
+		{"1", "noext", "noext_2"},
+	}
+	for _, tt := range tests {
+		if got := uniqueName(used, tt.scope, tt.name); got != tt.want {
+			t.Errorf("uniqueName(%q, %q) = %q，期望 %q", tt.scope, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackageSize(t *testing.T) {
+	tests := []struct {
+		files []JobFile
+		want  int64
+	}{
+		{nil, 0},
+		{[]JobFile{{Size: 10}, {Size: 20}, {Size: -5}}, 30},
+		{[]JobFile{{Size: math.MaxInt64}, {Size: 1}}, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		if got := packageSize(tt.files); got != tt.want {
+			t.Errorf("packageSize(%+v) = %d，期望 %d", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestFetchJobFileNoQuota(t *testing.T) {
+	// 剩余额度为0时不能发起请求，FetchJobFile 的 maxBytes 为0表示不限制
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	if _, err := fetchJobFileTo(DownloadJob{JobID: "job1"}, JobFile{Name: "a.jpg"}, path, 0); err == nil {
+		t.Fatal("没有剩余额度时应当失败")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("没有剩余额度时不应创建文件")
+	}
+}
@@ -0,0 +1,30 @@
+package jmcomic
+
+import (
+	"errors"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+// errorMessages 错误码对应的提示，发送给用户的错误信息只使用这里的文字
+var errorMessages = map[sdk.ErrorCode]string{
+	sdk.ErrCodeNotFound:           "找不到对应的内容，请检查ID是否正确",
+	sdk.ErrCodeRegionBlocked:      "该内容在当前地区不可访问",
+	sdk.ErrCodeTimeout:            "请求超时，请稍后再试",
+	sdk.ErrCodeBackendUnavailable: "服务暂时不可用，请稍后再试",
+	sdk.ErrCodeInvalidID:          "ID格式不正确",
+	sdk.ErrCodeRateLimited:        "请求过于频繁，请稍后再试",
+	sdk.ErrCodeBadRequest:         "请求参数有误",
+	sdk.ErrCodeInternal:           "服务出现错误，请稍后再试",
+}
+
+// renderError 使用 error 模板将错误转换为发送给用户的提示，例如 "搜索失败: 请求超时，请稍后再试"
+// 原始错误信息不会出现在提示中 (ID格式错误除外，它说明了输入哪里不对)，调用方应自行记录日志
+func renderError(action string, err error) string {
+	msg := errorMessages[sdk.CodeOf(err)]
+	var idErr *sdk.IDError
+	if errors.As(err, &idErr) {
+		msg = cleanBlock(idErr.Error(), 0)
+	}
+	return renderText(tmplError, errorView{Action: action, Message: msg})
+}
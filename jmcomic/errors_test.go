@@ -0,0 +1,69 @@
+package jmcomic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+func TestRenderError(t *testing.T) {
+	apiErr := func(code sdk.ErrorCode) error {
+		return &sdk.APIError{Code: code, StatusCode: 500, Message: "原始信息 /data/secret"}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not_found", apiErr(sdk.ErrCodeNotFound), "找不到对应的内容，请检查ID是否正确"},
+		{"region_blocked", apiErr(sdk.ErrCodeRegionBlocked), "该内容在当前地区不可访问"},
+		{"timeout", apiErr(sdk.ErrCodeTimeout), "请求超时，请稍后再试"},
+		{"backend_unavailable", apiErr(sdk.ErrCodeBackendUnavailable), "服务暂时不可用，请稍后再试"},
+		{"invalid_id", apiErr(sdk.ErrCodeInvalidID), "ID格式不正确"},
+		{"rate_limited", apiErr(sdk.ErrCodeRateLimited), "请求过于频繁，请稍后再试"},
+		{"bad_request", apiErr(sdk.ErrCodeBadRequest), "请求参数有误"},
+		{"internal", apiErr(sdk.ErrCodeInternal), "服务出现错误，请稍后再试"},
+		{"包装后的错误", fmt.Errorf("查询失败: %w", apiErr(sdk.ErrCodeNotFound)), "找不到对应的内容，请检查ID是否正确"},
+		{"context超时", context.DeadlineExceeded, "请求超时，请稍后再试"},
+		{"其他错误", errors.New("解析失败 /data/secret"), "服务出现错误，请稍后再试"},
+	}
+	for _, tt := range tests {
+		got := renderError("搜索失败", tt.err)
+		if want := "搜索失败: " + tt.want; got != want {
+			t.Errorf("%s: renderError = %q，期望 %q", tt.name, got, want)
+		}
+		if strings.Contains(got, "secret") {
+			t.Errorf("%s: 提示中包含原始错误信息: %q", tt.name, got)
+		}
+	}
+}
+
+func TestRenderIDError(t *testing.T) {
+	_, err := sdk.ParseAlbumID("abc")
+	if err == nil {
+		t.Fatal("ParseAlbumID(\"abc\") 应当失败")
+	}
+	// ID格式错误说明了输入哪里不对，原样显示
+	if got, want := renderError("查询失败", err), "查询失败: "+cleanBlock(err.Error(), 0); got != want {
+		t.Fatalf("renderError = %q，期望 %q", got, want)
+	}
+}
+
+func TestErrorMessagesComplete(t *testing.T) {
+	codes := []sdk.ErrorCode{
+		sdk.ErrCodeNotFound, sdk.ErrCodeRegionBlocked, sdk.ErrCodeTimeout, sdk.ErrCodeBackendUnavailable,
+		sdk.ErrCodeInvalidID, sdk.ErrCodeRateLimited, sdk.ErrCodeBadRequest, sdk.ErrCodeInternal,
+	}
+	if len(errorMessages) != len(codes) {
+		t.Fatalf("errorMessages 有 %d 项，期望 %d 项", len(errorMessages), len(codes))
+	}
+	for _, code := range codes {
+		if errorMessages[code] == "" {
+			t.Errorf("错误码 %s 没有对应的提示", code)
+		}
+	}
+}
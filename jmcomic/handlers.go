@@ -3,10 +3,12 @@ package jmcomic
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/packager"
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	"github.com/FloatTech/zbputils/control"
 	"github.com/FloatTech/zerobot/common/message"
 	zlog "github.com/FloatTech/zerobot/common/log"
 	"github.com/FloatTech/zerobot/handlers"
@@ -15,265 +17,487 @@ import (
 
 const (
 	cmdPrefix = "jm" // 硬编码命令前缀
+
+	maxJobsDisplay = 10 // jm jobs 最多显示的任务数
 )
 
-// handleGenericCommand 是一个通用的命令分发器，用于 "jm ..."
-// 它会检查 "jm" 后面的第一个词是什么，然后分发到具体的处理器
-// 或者如果第一个词看起来像漫画ID，则尝试直接下载
+// handleGenericCommand 是 "jm ..." 的命令分发器
+// 第一个参数是子命令时交给对应的处理器，是漫画ID或链接时显示详情或直接下载
 func handleGenericCommand(ctx *zero.Ctx) {
-	// 获取 "jm " 后面的所有文本
-	// ZeroBot 的 ctx.Event.RawMessage 或 ctx.Event.Message.ExtractPlainText()
-	// 或者如果命令注册时使用了 RegexCmd(true)，则 ctx.State["matched"]
-	// 假设注册方式为 engine.OnCommand("jm", zero.RegexCmd(true))
-	// 那么 ctx.State["matched"] 将包含 "jm" 后面的内容 (不含 "jm" 本身)
-	
-	// 更可靠的方式是依赖于 `ctx.Event.GetArgs()` 如果命令注册时指定了参数解析
-	// 或者自己解析 `ctx.Event.GetMessage()` (获取原始消息链)
-	
-	fullArgString := ""
-	// 尝试从 ctx.State["matched"] 获取 (如果使用 RegexCmd(true) 且命令是 "jm")
-	if matched, ok := ctx.State["matched"].(string); ok {
-		fullArgString = strings.TrimSpace(matched)
-	} else {
-		// 后备：尝试从原始消息中提取 "jm "之后的内容
-		rawMsg := ctx.Event.GetMessage().ExtractPlainText()
-		if strings.HasPrefix(strings.ToLower(rawMsg), cmdPrefix+" ") {
-			fullArgString = strings.TrimSpace(rawMsg[len(cmdPrefix)+1:])
-		} else if strings.ToLower(rawMsg) == cmdPrefix { // 用户只发送了 "jm"
-			handleHelp(ctx) // 显示帮助
-			return
-		} else {
-			// 如果消息不是以 "jm " 开头，理论上不应该进入这个处理器
-			// 但为了健壮性，可以记录一下
-			zlog.Warnf("[%s] Generic handler called with unexpected message: %s", pluginName, rawMsg)
-			return
-		}
+	// OnCommand 匹配后 ctx.State["args"] 为 "jm" 后面的内容
+	fullArgString, _ := ctx.State["args"].(string)
+	fullArgString = strings.TrimSpace(fullArgString)
+	if fullArgString == "" { // 用户只发送了 "jm"
+		handleHelp(ctx, nil)
+		return
 	}
 
-	if fullArgString == "" { // 用户只发送了 "jm" (已被上面的分支处理，这里是双重检查)
-		handleHelp(ctx)
+	parts, err := splitArgs(fullArgString)
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
 		return
 	}
-
-	parts := strings.Fields(fullArgString)
-	if len(parts) == 0 { // 理论上不会发生，因为 fullArgString 非空
-		handleHelp(ctx)
+	if len(parts) == 0 { // 只有空的引号等情况
+		handleHelp(ctx, nil)
 		return
 	}
 
-	command := strings.ToLower(parts[0])
-	args := parts[1:]
-
-	switch command {
-	case "help":
-		handleHelp(ctx)
-	case "search":
-		handleSearchComic(ctx, args)
-	case "detail":
-		handleComicDetail(ctx, args)
-	case "download": // "jm download <albumID> <chapterIDs...>"
-		handleDownloadChapters(ctx, args)
-	default:
-		// 如果第一个参数不是已知的子命令，我们检查它是否可能是漫画ID
-		// 这是一个简化的ID检查，实际JM ID可能有特定格式 (如纯数字，或带前缀)
-		// 我们假设漫画ID是数字或者包含数字，并且后面可能跟着章节ID
-		// 例如: "jm 12345 67890" -> albumID=12345, chapterIDs=[67890]
-		//       "jm JM12345 ch1 ch2" -> albumID=JM12345, chapterIDs=[ch1, ch2]
-		if len(parts) >= 2 && isPotentialAlbumID(parts[0]) { // parts[0]是潜在albumID, parts[1:]是潜在chapterIDs
-			// 将 parts[0] 作为 albumID，parts[1:] 作为 chapterIDs 调用下载处理器
-			// 注意：这里我们复用 handleDownloadChapters，但它期望的 args 是 [albumID, chapterID1, chapterID2...]
-			// 所以我们需要重新构造一下参数
-			downloadArgs := make([]string, 0, len(parts))
-			downloadArgs = append(downloadArgs, parts[0]) // albumID
-			downloadArgs = append(downloadArgs, parts[1:]...) // chapterIDs
-			handleDownloadChapters(ctx, downloadArgs) // 直接调用下载
-		} else {
-			// 如果不匹配任何已知命令，也不是直接下载格式，则显示帮助
-			ctx.SendChain(message.Text(fmt.Sprintf("未知命令 '%s' 或格式错误。\n发送 '%s help' 查看帮助。", command, cmdPrefix)))
-		}
+	if c, ok := commands.lookup(parts[0]); ok {
+		commands.run(ctx, c, parts[1:])
+		return
 	}
-}
-
-// isPotentialAlbumID 简单检查字符串是否可能是漫画ID (例如包含数字)
-// 你可能需要根据实际的JM漫画ID格式来改进这个函数
-var albumIDRegex = regexp.MustCompile(`(jm)?\d+`) // 匹配 jm12345 或 12345 这样的格式
-func isPotentialAlbumID(s string) bool {
-	return albumIDRegex.MatchString(strings.ToLower(s))
-	// 或者更简单的：
-	// for _, r := range s {
-	// 	if unicode.IsDigit(r) {
-	// 		return true
-	// 	}
-	// }
-	// return false
-}
-
-
-// handleHelp 显示帮助信息
-func handleHelp(ctx *zero.Ctx) {
-	helpMsg := fmt.Sprintf("%s 插件帮助 (JMComic):\n"+
-		"1. %s help - 显示此帮助信息\n"+
-		"2. %s search <关键词> - 搜索漫画\n"+
-		"3. %s detail <漫画ID> - 获取漫画详情\n"+
-		"4. %s download <漫画ID> <章节ID1> [章节ID2...] - 下载指定章节\n"+
-		"   或直接: %s <漫画ID> <章节ID1> [章节ID2...] - 快速下载",
-		strings.ToTitle(pluginName), cmdPrefix, cmdPrefix, cmdPrefix, cmdPrefix, cmdPrefix)
-	ctx.SendChain(message.Text(helpMsg))
+	// 第一个参数不是已知的子命令时，检查它是否是漫画ID或链接
+	// 例如: "jm 12345" -> 显示详情; "jm 12345 1-3" -> 下载漫画12345的第1-3章; "jm 12345 latest" -> 下载最新一章
+	//       "jm https://18comic.vip/photo/67890" 这样的章节链接后面不需要章节ID
+	if _, err := parseIDRef(parts[0]); err != nil {
+		ctx.SendChain(message.Text(fmt.Sprintf("未知命令 '%s' 或格式错误。\n发送 '%s help' 查看帮助。", sanitizeText(parts[0], maxShortRunes), cmdPrefix)))
+		return
+	}
+	name := "download" // 直接调用下载
+	if len(parts) == 1 {
+		name = "detail"
+	}
+	c, _ := commands.lookup(name)
+	commands.run(ctx, c, parts)
 }
 
 // handleSearchComic 处理搜索漫画命令
-// args 是 "search" 后面的参数列表
+// args 是 "search" 后面的参数列表，支持 --page/--sort/--time/--category/--by 选项
 func handleSearchComic(ctx *zero.Ctx, args []string) {
-	if len(args) == 0 {
-		ctx.SendChain(message.Text("请输入搜索关键词！例如: " + cmdPrefix + " search <关键词>"))
+	opts, err := parseSearchOptions(args)
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
 		return
 	}
-	keyword := strings.Join(args, " ")
 
 	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
 	defer cancel()
 
-	ctx.SendChain(message.Text(fmt.Sprintf("正在搜索漫画: %s ...", keyword)))
-	results, err := SearchComic(reqCtx, keyword)
+	ctx.SendChain(message.Text(fmt.Sprintf("正在搜索漫画: %s ...", describeSearchOptions(opts))))
+	result, err := api.Search(reqCtx, opts)
 	if err != nil {
-		zlog.Errorf("[%s Handler] 搜索 '%s' 失败: %v", pluginName, keyword, err)
-		errMsg := fmt.Sprintf("搜索失败: %v", err)
-		if len(errMsg) > 100 {
-			errMsg = errMsg[:100] + "..."
-		}
-		ctx.SendChain(message.Text(errMsg))
+		zlog.Errorf("[%s Handler] 搜索 '%s' 失败: %v", pluginName, opts.Keyword, err)
+		ctx.SendChain(message.Text(renderError("搜索失败", err)))
 		return
 	}
 
-	if len(results) == 0 {
-		ctx.SendChain(message.Text(fmt.Sprintf("未找到与 '%s' 相关的漫画。", keyword)))
+	if len(result.Items) == 0 {
+		if opts.Page > 1 {
+			ctx.SendChain(message.Text(fmt.Sprintf("'%s' 的第 %d 页没有结果。", sanitizeText(opts.Keyword, maxTitleRunes), opts.Page)))
+		} else {
+			ctx.SendChain(message.Text(fmt.Sprintf("未找到与 '%s' 相关的漫画。", sanitizeText(opts.Keyword, maxTitleRunes))))
+		}
 		return
 	}
 
-	var msgChain message.Chain
-	msgChain = msgChain.Add(message.Text(fmt.Sprintf("找到 %d 个结果:\n", len(results))))
-
-	for i, comic := range results {
-		if i >= cfg.MaxSearchResultsDisplay {
-			msgChain = msgChain.Add(message.Text(fmt.Sprintf("...等共 %d 个结果。\n", len(results))))
-			break
-		}
-		comicInfo := fmt.Sprintf("%d. %s (ID: %s)\n   作者: %s\n", i+1, comic.Title, comic.ID, comic.Author)
-		msgChain = msgChain.Add(message.Text(comicInfo))
-		// 封面图发送逻辑 (可选)
-	}
-	msgChain = msgChain.Add(message.Text(fmt.Sprintf("\n使用 %s detail <漫画ID> 查看详情和章节。", cmdPrefix)))
-	ctx.SendChain(msgChain)
+	sendSearchResults(ctx, searchStates.put(sessionKey{groupID: ctx.Event.GroupID, userID: ctx.Event.UserID}, result))
 }
 
 // handleComicDetail 处理获取漫画详情命令
-// args 是 "detail" 后面的参数列表 (期望只有一个：漫画ID)
+// args 是 "detail" 后面的参数列表 (只有一个：漫画ID，由命令注册表检查)
 func handleComicDetail(ctx *zero.Ctx, args []string) {
-	if len(args) == 0 {
-		ctx.SendChain(message.Text("请输入漫画ID！例如: " + cmdPrefix + " detail <漫画ID>"))
+	ref, ok := resolveArg(ctx, args[0])
+	if !ok {
 		return
 	}
-	albumID := args[0]
+	showComicDetail(ctx, string(ref.AlbumID), "")
+}
 
+// showComicDetail 获取并发送漫画详情，成功时返回详情供交互式选择使用
+// footer 非空时替换默认的下载用法提示
+func showComicDetail(ctx *zero.Ctx, albumID, footer string) (*ComicDetail, bool) {
 	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
 	defer cancel()
 
 	ctx.SendChain(message.Text(fmt.Sprintf("正在获取漫画 %s 的详情...", albumID)))
-	detail, err := GetComicDetail(reqCtx, albumID)
+	detail, err := api.Detail(reqCtx, albumID)
 	if err != nil {
 		zlog.Errorf("[%s Handler] 获取详情 '%s' 失败: %v", pluginName, albumID, err)
-		errMsg := fmt.Sprintf("获取详情失败: %v", err)
-		if len(errMsg) > 100 {
-			errMsg = errMsg[:100] + "..."
-		}
-		ctx.SendChain(message.Text(errMsg))
-		return
-	}
-
-	var msgChain message.Chain
-	titleInfo := fmt.Sprintf("漫画: %s (ID: %s)\n作者: %s\n标签: %s\n", detail.Title, detail.ID, detail.Author, detail.Tags)
-	msgChain = msgChain.Add(message.Text(titleInfo))
-	
-	desc := detail.Description
-	if len(desc) > 200 {
-		desc = desc[:200] + "..."
+		ctx.SendChain(message.Text(renderError("获取详情失败", err)))
+		return nil, false
 	}
-	msgChain = msgChain.Add(message.Text(fmt.Sprintf("简介: %s\n", desc)))
-	// 封面图 (可选)
 
-	msgChain = msgChain.Add(message.Text("\n章节列表 (部分):\n"))
-	for i, chapter := range detail.Chapters {
-		if i >= cfg.MaxChaptersDisplay {
-			msgChain = msgChain.Add(message.Text(fmt.Sprintf("...等共 %d 个章节。\n", len(detail.Chapters))))
-			break
+	var covers map[string]message.Segment
+	if coversEnabled(ctx.Event.GroupID) {
+		coverCtx, coverCancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+		if cover, ok := coverSegment(coverCtx, detail.ID); ok {
+			covers = map[string]message.Segment{detail.ID: cover}
 		}
-		chapterInfo := fmt.Sprintf("%d. %s (章节ID: %s, 页数: %d)\n", i+1, chapter.Title, chapter.ID, chapter.PageCount)
-		msgChain = msgChain.Add(message.Text(chapterInfo))
+		coverCancel()
 	}
-	msgChain = msgChain.Add(message.Text(fmt.Sprintf("\n使用 %s download %s <章节ID1> ... 或直接 %s %s <章节ID1> ... 下载。", cmdPrefix, albumID, cmdPrefix, albumID)))
-	ctx.SendChain(msgChain)
+	if footer == "" {
+		footer = fmt.Sprintf("使用 %s download %s <章节序号> (如 1 3 5-8、latest) 或直接 %s %s <章节序号> 下载，章节ID需加 id: 前缀。", cmdPrefix, albumID, cmdPrefix, albumID)
+	}
+	view := detailView{ComicDetail: *detail, MaxChapters: cfg.MaxChaptersDisplay, Footer: footer}
+	view.Chapters = orderedChapters(detail.Chapters) // 与章节选择的序号一致
+	ctx.SendChain(renderChain(tmplDetail, view, covers))
+	return detail, true
 }
 
 // handleDownloadChapters 处理下载章节命令
-// args 是 "download" 后面的参数，或者直接是 "jm" 后面的参数 (albumID, chapterIDs...)
+// args 是 "download" 后面的参数，或者直接是 "jm" 后面的参数 (albumID, 章节...)
+// 只有漫画ID时下载整本漫画，预计页数超过 download_confirm_pages 时需要用户回复确认
+// 下载任务提交后立即返回任务ID，后台轮询任务状态并在结束时通知用户
 func handleDownloadChapters(ctx *zero.Ctx, args []string) {
-	if len(args) < 2 { // 至少需要 albumID 和一个 chapterID
-		ctx.SendChain(message.Text(fmt.Sprintf("参数不足！格式: %s [download] <漫画ID> <章节ID1> [章节ID2...]", cmdPrefix)))
+	args, format, err := extractFormatFlag(args)
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
 		return
 	}
+	if len(args) == 0 {
+		sendUsage(ctx, "download")
+		return
+	}
+	selection, err := parseChapterArgs(args[1:])
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
+		return
+	}
+	ref, ok := resolveArg(ctx, args[0])
+	if !ok {
+		return
+	}
+	albumID := string(ref.AlbumID)
+	if selection.empty() {
+		if ref.PhotoID != "" { // 只给出章节链接时下载该章节
+			selection.ids = append(selection.ids, ref.PhotoID)
+		} else {
+			selection = wholeAlbumArgs()
+		}
+	}
 
-	albumID := args[0]
-	chapterIDs := args[1:]
-
-	if len(chapterIDs) == 0 { // 理论上已被上面的检查覆盖
-		ctx.SendChain(message.Text("请输入至少一个章节ID进行下载！"))
+	chapters, ok := resolveDownloadChapters(ctx, albumID, selection)
+	if !ok {
+		return
+	}
+	if !selection.wholeAlbum() {
+		submitDownload(ctx, albumID, format, chapters, false)
+		return
+	}
+	if cfg.DownloadConfirmPages > 0 && estimatePages(chapters) > cfg.DownloadConfirmPages {
+		prompt := fmt.Sprintf("将下载漫画 %s 的全部 %d 个章节，%s，超过 %d 页。", albumID, len(chapters), estimatePagesText(chapters), cfg.DownloadConfirmPages)
+		// 与选择会话一样在单独的 goroutine 中等待确认，不占用命令处理器
+		go func() {
+			defer recoverSession("确认下载会话")
+			if confirmDownload(ctx, prompt) {
+				submitDownload(ctx, albumID, format, chapters, true)
+			}
+		}()
 		return
 	}
+	submitDownload(ctx, albumID, format, chapters, true)
+}
 
-	downloadTimeout := cfg.timeoutDuration * time.Duration(len(chapterIDs))
-	if downloadTimeout > 3*time.Minute {
-		downloadTimeout = 3 * time.Minute
+// submitDownload 提交下载任务并在任务结束后通知用户
+// 整本下载时请求中的章节列表为空，由API服务下载全部章节
+func submitDownload(ctx *zero.Ctx, albumID string, format packager.Format, chapters []ChapterInfo, wholeAlbum bool) {
+	chapterIDs := make([]string, 0, len(chapters))
+	for _, chapter := range chapters {
+		chapterIDs = append(chapterIDs, chapter.ID)
+	}
+	pages := estimatePagesText(chapters)
+	var requestIDs []string
+	if !wholeAlbum {
+		requestIDs = chapterIDs
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	defer cancel()
+
+	ctx.SendChain(message.Text(fmt.Sprintf("正在为漫画 %s 提交 %d 个章节 (%s) 的下载请求...", albumID, len(chapterIDs), pages)))
+
+	status, err := api.Download(reqCtx, albumID, requestIDs)
+	if err != nil {
+		zlog.Errorf("[%s Handler] 下载漫画 '%s' 章节 %v 失败: %v", pluginName, albumID, chapterIDs, err)
+		ctx.SendChain(message.Text(renderError("下载请求失败", err)))
+		return
 	}
-	if downloadTimeout < cfg.timeoutDuration {
-		downloadTimeout = cfg.timeoutDuration
+
+	job := &DownloadJob{
+		JobID:       status.JobID,
+		Backend:     status.Backend,
+		AlbumID:     albumID,
+		ChapterIDs:  chapterIDs,
+		UserID:      ctx.Event.UserID,
+		GroupID:     ctx.Event.GroupID,
+		Format:      string(format),
+		SubmittedAt: time.Now(),
+		CheckedAt:   time.Now(),
+		Status:      *status,
 	}
+	jobs.add(job)
+
+	ctx.SendChain(message.Text(renderText(tmplDownload, downloadView{
+		JobID: status.JobID, AlbumID: albumID, ChapterIDs: chapterIDs, Chapters: chapters, Message: status.Message, Format: string(format),
+	})))
+
+	// 任务结束时插件可能已在本群禁用，通知和文件发送前都会检查
+	jobs.watch(job.JobID, func(job DownloadJob, timedOut bool) {
+		if timedOut {
+			notifyIfEnabled(ctx, fmt.Sprintf("下载任务 %s (漫画 %s) 在 %v 内未完成，已停止自动跟踪。\n可稍后使用 %s status %s 手动查询。", job.JobID, job.AlbumID, cfg.jobMaxWait, cmdPrefix, job.JobID))
+			return
+		}
+		if !notifyIfEnabled(ctx, formatJobDone(job)) {
+			return
+		}
+		if cfg.DeliverFiles && job.Status.State == JobStateCompleted {
+			deliverJobFiles(ctx, job)
+		}
+	})
+}
 
-	reqCtx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+// resolveDownloadChapters 获取漫画详情，把章节参数解析为具体章节
+// 只给出章节ID时详情只用于显示章节标题，获取失败也会继续下载；按序号选择时获取失败则提示用户
+func resolveDownloadChapters(ctx *zero.Ctx, albumID string, selection chapterArgs) ([]ChapterInfo, bool) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
 	defer cancel()
+	var chapters []ChapterInfo
+	detail, err := api.Detail(reqCtx, albumID)
+	switch {
+	case err == nil:
+		chapters = detail.Chapters
+	case len(selection.selection.selectors) > 0:
+		zlog.Errorf("[%s Handler] 获取漫画 '%s' 的章节列表失败: %v", pluginName, albumID, err)
+		ctx.SendChain(message.Text(renderError("获取章节列表失败", err)))
+		return nil, false
+	default:
+		zlog.Warnf("[%s Handler] 获取漫画 '%s' 的详情失败，将不显示章节标题: %v", pluginName, albumID, err)
+	}
+	resolved, err := selection.resolve(chapters)
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
+		return nil, false
+	}
+	return resolved, true
+}
 
-	ctx.SendChain(message.Text(fmt.Sprintf("正在为漫画 %s 提交章节 %v 的下载请求...", albumID, chapterIDs)))
+// downloadFlags jm download 的选项
+var downloadFlags = []flagSpec{{name: "format", short: "f", value: "pdf|cbz|zip"}}
 
-	apiMsg, pathHint, err := DownloadChapters(reqCtx, albumID, chapterIDs)
+// extractFormatFlag 从参数中取出 "--format <格式>" (或 "-f <格式>")，返回剩余参数
+// 未指定格式时返回空格式
+func extractFormatFlag(args []string) ([]string, packager.Format, error) {
+	rest, flags, err := parseFlags(args, downloadFlags)
+	if err != nil {
+		return nil, "", err
+	}
+	value, ok := flags["format"]
+	if !ok {
+		return rest, "", nil
+	}
+	format, err := packager.ParseFormat(value)
 	if err != nil {
-		zlog.Errorf("[%s Handler] 下载漫画 '%s' 章节 %v 失败: %v. API消息: %s", pluginName, albumID, chapterIDs, err, apiMsg)
-		errMsg := fmt.Sprintf("下载请求失败: %v", err)
-		if apiMsg != "" {
-			errMsg = fmt.Sprintf("下载请求失败: %s", apiMsg)
+		return nil, "", err
+	}
+	return rest, format, nil
+}
+
+// formatJobDone 生成任务结束时发送给用户的通知
+func formatJobDone(job DownloadJob) string {
+	return renderText(tmplJobDone, newJobView(job))
+}
+
+// newJobView 生成模板中使用的任务数据
+func newJobView(job DownloadJob) jobView {
+	view := jobView{
+		DownloadJob: job,
+		State:       jobStateText(job.Status.State),
+		Failed:      job.Status.State == JobStateFailed,
+		Deliver:     cfg.DeliverFiles,
+	}
+	if view.Failed {
+		view.Error = jobErrorText(job.Status)
+	}
+	return view
+}
+
+// jobErrorText 返回失败任务的错误提示，原始错误信息只记录在日志中
+func jobErrorText(status JobStatus) string {
+	if msg, ok := errorMessages[sdk.ErrorCode(status.ErrorCode)]; ok {
+		return msg
+	}
+	return errorMessages[sdk.ErrCodeInternal]
+}
+
+// jobStateText 返回任务状态的中文描述
+func jobStateText(state JobState) string {
+	switch state {
+	case JobStateQueued:
+		return "排队中"
+	case JobStateRunning:
+		return "下载中"
+	case JobStateCompleted:
+		return "已完成"
+	case JobStateFailed:
+		return "失败"
+	default:
+		return string(state)
+	}
+}
+
+// handleJobStatus 处理查询下载任务状态命令
+// args 是 "status" 后面的参数列表 (只有一个：任务ID，由命令注册表检查)
+func handleJobStatus(ctx *zero.Ctx, args []string) {
+	jobID := args[0]
+
+	job, ok := jobs.get(jobID)
+	if !ok || !job.Status.State.IsTerminal() {
+		// 本地没有记录或任务尚未结束时，向API服务查询最新状态
+		reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+		defer cancel()
+		refreshed, err := jobs.refresh(reqCtx, jobID)
+		if err != nil {
+			zlog.Errorf("[%s Handler] 查询任务 '%s' 状态失败: %v", pluginName, jobID, err)
+			ctx.SendChain(message.Text(renderError("查询任务状态失败", err)))
+			return
+		}
+		job = refreshed
+	}
+
+	ctx.SendChain(message.Text(renderText(tmplJobStatus, newJobView(job))))
+}
+
+// handleListJobs 列出当前会话 (群聊或私聊) 中提交的下载任务
+func handleListJobs(ctx *zero.Ctx) {
+	list := jobs.listByChat(ctx.Event.GroupID, ctx.Event.UserID)
+	if len(list) == 0 {
+		ctx.SendChain(message.Text("当前会话没有下载任务。"))
+		return
+	}
+
+	views := make([]jobView, len(list))
+	for i, job := range list {
+		views[i] = newJobView(job)
+	}
+	ctx.SendChain(message.Text(renderText(tmplJobs, jobsView{Jobs: views, Max: maxJobsDisplay})))
+}
+
+// handleCache 处理缓存管理命令
+// args 是 "cache" 后面的参数列表: stats (默认) 或 clear
+func handleCache(ctx *zero.Ctx, args []string) {
+	action := "stats"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	switch action {
+	case "stats":
+		ctx.SendChain(message.Text(fmt.Sprintf("缓存统计:\n搜索: %s\n详情: %s", searchCache.stats(), detailCache.stats())))
+	case "clear":
+		n := clearCaches()
+		zlog.Infof("[%s Handler] 用户 %d 清空了缓存 (%d 项)", pluginName, ctx.Event.UserID, n)
+		ctx.SendChain(message.Text(fmt.Sprintf("已清空缓存，共 %d 项。", n)))
+	default:
+		ctx.SendChain(message.Text(fmt.Sprintf("未知操作 '%s'。", sanitizeText(action, maxShortRunes))))
+		sendUsage(ctx, "cache")
+	}
+}
+
+// handleBackend 查看API后端状态或停用、恢复后端
+// 停用的后端不再接收新请求，已提交到该后端的任务仍会继续查询直到结束
+func handleBackend(ctx *zero.Ctx, args []string) {
+	if len(args) == 0 {
+		backends := apiClient.Backends()
+		if len(backends) == 0 {
+			ctx.SendChain(message.Text("未配置API后端。"))
+			return
 		}
-		if len(errMsg) > 150 {
-			errMsg = errMsg[:150] + "..."
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("API后端 (策略: %s):\n", cfg.BackendStrategy))
+		for i, b := range backends {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, backendStatusText(b)))
 		}
-		ctx.SendChain(message.Text(errMsg))
+		ctx.SendChain(message.Text(strings.TrimSpace(sb.String())))
+		return
+	}
+
+	action := strings.ToLower(args[0])
+	if (action != "drain" && action != "undrain") || len(args) < 2 {
+		sendUsage(ctx, "backend")
+		return
+	}
+	url, ok := apiClient.SetBackendDrained(args[1], action == "drain")
+	if !ok {
+		ctx.SendChain(message.Text(fmt.Sprintf("找不到后端 '%s'，发送 %s backend 查看列表。", sanitizeText(args[1], maxURLRunes), cmdPrefix)))
+		return
+	}
+	zlog.Infof("[%s Handler] 用户 %d 对后端 %s 执行了 %s", pluginName, ctx.Event.UserID, url, action)
+	if action == "drain" {
+		ctx.SendChain(message.Text(fmt.Sprintf("已停用后端 %s，新请求将分配到其他后端。", url)))
+	} else {
+		ctx.SendChain(message.Text(fmt.Sprintf("已恢复后端 %s。", url)))
+	}
+}
+
+// backendStatusText 返回一个API后端状态的描述
+func backendStatusText(b sdk.BackendStatus) string {
+	state := "正常"
+	switch {
+	case b.Drained:
+		state = "已停用"
+	case b.BreakerOpen:
+		state = "熔断中"
+	case !b.Healthy:
+		state = "不健康"
+	}
+	latency := "-"
+	if b.Latency > 0 {
+		latency = b.Latency.Round(time.Millisecond).String()
+	}
+	line := fmt.Sprintf("%s [%s] 权重 %d, 延迟 %s, 请求 %d, 失败 %d", b.URL, state, b.Weight, latency, b.Requests, b.Failures)
+	if b.LastError != "" && state != "正常" {
+		line += "\n   最近错误: " + b.LastError
+	}
+	return line
+}
+
+// handleAdmin 处理管理命令
+// args 是 "admin" 后面的参数列表: client [html|api|auto] 或 metrics
+func handleAdmin(ctx *zero.Ctx, args []string) {
+	action := strings.ToLower(args[0])
+	if action == "metrics" {
+		ctx.SendChain(message.Text(metricsText()))
+		return
+	}
+	if action != "client" {
+		sendUsage(ctx, "admin")
+		return
+	}
+	if len(args) == 1 {
+		ctx.SendChain(message.Text(clientTypeText(apiClient.ClientType())))
+		return
+	}
+	mode := strings.ToLower(args[1])
+	if err := apiClient.SetClientType(mode); err != nil {
+		ctx.SendChain(message.Text(fmt.Sprintf("未知的客户端类型 '%s'，可选: html, api, auto", sanitizeText(args[1], maxShortRunes))))
 		return
 	}
+	zlog.Infof("[%s Handler] 用户 %d 将客户端类型设置为 %s", pluginName, ctx.Event.UserID, mode)
+	ctx.SendChain(message.Text(fmt.Sprintf("已设置。%s\n(重启后恢复为配置文件中的设置)", clientTypeText(apiClient.ClientType()))))
+}
 
-	responseMsg := fmt.Sprintf("下载请求已提交: %s", apiMsg)
-	if pathHint != "" {
-		responseMsg += fmt.Sprintf("\n提示: 文件可能保存在API服务器的 %s 目录中。", pathHint)
+// clientTypeText 返回客户端类型状态的描述
+func clientTypeText(st sdk.ClientTypeStatus) string {
+	if st.Mode != sdk.ClientAuto {
+		return fmt.Sprintf("客户端类型: 固定为 %s", st.Mode)
+	}
+	if time.Now().Before(st.Until) {
+		return fmt.Sprintf("客户端类型: 自动 (%s 最近失败，当前使用 %s，%v 后重新尝试 %s)",
+			st.Preferred, st.Current, time.Until(st.Until).Round(time.Second), st.Preferred)
 	}
-	responseMsg += "\n请注意：下载在API服务器端进行，完成后文件不会直接发送给您，需从服务器获取。"
-	ctx.SendChain(message.Text(responseMsg))
+	return fmt.Sprintf("客户端类型: 自动 (当前使用 %s)", st.Preferred)
 }
 
-// MustRegisterHandlers 注册命令处理器到指定的引擎
+// MustRegisterHandlers 注册命令处理器
+// engine 是控制管理器中的引擎，按群启用/禁用；raw 是插件加载时的引擎，只用于在禁用的群中重新启用
 // 这个函数由 jmcomic.go 中的 init -> OnLoad 调用
-func MustRegisterHandlers(engine *zero.Engine) {
-	// 我们注册一个顶级的 "jm" 命令，它会捕获所有 "jm ..." 的消息
-	// 然后在 handleGenericCommand 中进行二次分发
-	// 使用 RegexCmd(true) 使 ctx.State["matched"] 包含 "jm" 后面的所有内容
-	// 或者不使用 RegexCmd，然后在 handleGenericCommand 中解析原始消息
-	// 为了简单和灵活，我们让 handleGenericCommand 自己解析参数
-	engine.OnCommand(cmdPrefix, zero.OnlyToMe(false)).SetBlock(true).Handle(handlers.NewCtxCmd(handleGenericCommand))
-	
+func MustRegisterHandlers(engine *control.Engine, raw *zero.Engine) {
+	// 注册顶级的 "jm" 命令，由 handleGenericCommand 按子命令二次分发
+	engine.OnCommand(cmdPrefix, zero.OnlyToMe(false), commandRule).SetBlock(true).Handle(handlers.NewCtxCmd(handleGenericCommand))
 	zlog.Infof("[%s] 主命令 '%s' 已注册，将进行二次分发。", pluginName, cmdPrefix)
+
+	// 自动识别群聊中的漫画ID，不阻断其他插件处理同一条消息
+	engine.OnMessage(zero.OnlyGroup, autoRecognizeRule).SetBlock(false).Handle(handlers.NewCtxCmd(handleAutoRecognize))
+
+	// 禁用后受管理的引擎不再响应，jm enable 需要单独注册
+	raw.OnMessage(zero.OnlyGroup, reenableRule).SetBlock(true).Handle(handlers.NewCtxCmd(handleReenable))
 }
@@ -1,11 +1,17 @@
 package jmcomic
 
 import (
+	"sync"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
 	"github.com/FloatTech/zerobot/common/plugin"
 	zlog "github.com/FloatTech/zerobot/common/log"
 	zero "github.com/FloatTech/zerobot/core"
 )
 
+// registerOnce 控制管理器中的服务和命令处理器只注册一次，重新加载插件时不会重复注册
+var registerOnce sync.Once
+
 // JMComicPlugin 结构体，可以包含插件的状态或配置（如果需要）
 // 对于这个插件，大部分配置通过包级变量 cfg 管理
 type JMComicPlugin struct{}
@@ -34,17 +40,22 @@ func (p *JMComicPlugin) Description() string {
 // 在这里进行命令注册等初始化操作
 func (p *JMComicPlugin) OnLoad(e *zero.Engine) {
 	zlog.Infof("[%s] OnLoad called. Registering handlers...", pluginName)
-	// 确保httpClient的超时已经根据cfg设置
-	if httpClient.Timeout != cfg.timeoutDuration {
-		httpClient.Timeout = cfg.timeoutDuration
-		zlog.Debugf("[%s] HTTP client timeout set to %v in OnLoad", pluginName, cfg.timeoutDuration)
-	}
-	MustRegisterHandlers(e) // 将引擎实例传递给处理器注册函数
+	apiClient = sdk.NewClient(cfg.sdkConfig())
+	api = sanitizedAPI{cachedAPI{apiClient}}
+	initCaches()
+	templates = loadTemplates(cfg.TemplateDir)
+	registerOnce.Do(func() {
+		MustRegisterHandlers(registerService(), e) // 在控制管理器中注册后再注册处理器
+	})
 	zlog.Infof("[%s] Plugin (v%s by %s) loaded and handlers registered.", pluginName, pluginVersion, pluginAuthor)
 }
 
 // OnUnload 插件卸载时执行的函数 (可选)
 func (p *JMComicPlugin) OnUnload(e *zero.Engine) {
+	closeCaches()
+	if apiClient != nil {
+		apiClient.Close()
+	}
 	zlog.Infof("[%s] Plugin unloaded.", pluginName)
 }
 
@@ -0,0 +1,166 @@
+package jmcomic
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	zlog "github.com/FloatTech/zerobot/common/log"
+)
+
+// jobStore 保存插件端提交的下载任务
+// 任务状态由后台goroutine定期向API服务轮询更新
+type jobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*DownloadJob
+}
+
+var jobs = &jobStore{jobs: make(map[string]*DownloadJob)}
+
+// add 记录一个新提交的任务，并在超出 MaxJobsKept 时清理最早结束的任务
+func (s *jobStore) add(job *DownloadJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.JobID] = job
+	s.pruneLocked()
+}
+
+// pruneLocked 清理超出数量上限的已结束任务，未结束的任务不会被清理
+func (s *jobStore) pruneLocked() {
+	if len(s.jobs) <= cfg.MaxJobsKept {
+		return
+	}
+	finished := make([]*DownloadJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if job.Status.State.IsTerminal() {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].SubmittedAt.Before(finished[j].SubmittedAt)
+	})
+	for _, job := range finished {
+		if len(s.jobs) <= cfg.MaxJobsKept {
+			break
+		}
+		delete(s.jobs, job.JobID)
+	}
+}
+
+// get 返回任务的副本，避免调用方在锁外读写共享数据
+func (s *jobStore) get(jobID string) (DownloadJob, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return DownloadJob{}, false
+	}
+	return *job, true
+}
+
+// updateStatus 更新任务的最新状态
+func (s *jobStore) updateStatus(jobID string, status JobStatus) (DownloadJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return DownloadJob{}, false
+	}
+	job.Status = status
+	job.CheckedAt = time.Now()
+	return *job, true
+}
+
+// listByChat 返回在指定会话中提交的任务，按提交时间倒序
+// groupID 为0时表示私聊，此时按 userID 过滤
+func (s *jobStore) listByChat(groupID, userID int64) []DownloadJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]DownloadJob, 0)
+	for _, job := range s.jobs {
+		if groupID != 0 && job.GroupID != groupID {
+			continue
+		}
+		if groupID == 0 && (job.GroupID != 0 || job.UserID != userID) {
+			continue
+		}
+		result = append(result, *job)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SubmittedAt.After(result[j].SubmittedAt)
+	})
+	return result
+}
+
+// refresh 向API服务查询一次任务状态并更新记录
+func (s *jobStore) refresh(ctx context.Context, jobID string) (DownloadJob, error) {
+	if job, ok := s.get(jobID); ok {
+		ctx = sdk.WithBackend(ctx, job.Backend)
+	}
+	status, err := api.Job(ctx, jobID)
+	if err != nil {
+		return DownloadJob{}, err
+	}
+	job, ok := s.updateStatus(jobID, *status)
+	if !ok {
+		// 不是本插件实例提交的任务 (例如重启前提交的)，只返回查询到的状态
+		job = DownloadJob{JobID: status.JobID, AlbumID: status.AlbumID, ChapterIDs: status.ChapterIDs, CheckedAt: time.Now(), Status: *status}
+	}
+	return job, nil
+}
+
+// pollGetter 为每次轮询的请求加上 timeoutDuration 的超时
+type pollGetter struct {
+	sdk.JobGetter
+}
+
+// Job 查询任务状态
+func (g pollGetter) Job(ctx context.Context, jobID string) (*JobStatus, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, cfg.timeoutDuration)
+	defer cancel()
+	return g.JobGetter.Job(reqCtx, jobID)
+}
+
+// watch 在后台轮询任务状态，直到任务结束或超过 jobMaxWait
+// 任务结束或查询到无法重试的错误 (如API服务重启后任务不存在) 时调用 onDone，后者将任务记为失败；
+// 超时时以 timedOut=true 调用 onDone
+func (s *jobStore) watch(jobID string, onDone func(job DownloadJob, timedOut bool)) {
+	job, _ := s.get(jobID)
+	ctx, cancel := context.WithTimeout(sdk.WithBackend(context.Background(), job.Backend), cfg.jobMaxWait)
+	events := sdk.WatchJob(ctx, pollGetter{api}, jobID, cfg.jobPollInterval)
+	go func() {
+		defer cancel()
+		for event := range events {
+			if event.Err != nil && sdk.IsRetryable(event.Err) {
+				// 临时的轮询失败不终止任务，等待下一次轮询
+				zlog.Warnf("[%s Jobs] 查询任务 %s 状态失败: %v", pluginName, jobID, event.Err)
+				continue
+			}
+			status := event.Status
+			if event.Err != nil {
+				status.State = JobStateFailed
+				status.ErrorCode = string(sdk.CodeOf(event.Err))
+				status.Error = event.Err.Error()
+			}
+			job, ok := s.updateStatus(jobID, status)
+			if !ok {
+				return
+			}
+			if !job.Status.State.IsTerminal() {
+				continue
+			}
+			if job.Status.State == JobStateFailed {
+				zlog.Warnf("[%s Jobs] 任务 %s 失败 [%s]: %s", pluginName, jobID, job.Status.ErrorCode, job.Status.Error)
+			} else {
+				zlog.Infof("[%s Jobs] 任务 %s 已结束: %s", pluginName, jobID, job.Status.State)
+			}
+			onDone(job, false)
+			return
+		}
+		job, _ := s.get(jobID)
+		zlog.Warnf("[%s Jobs] 任务 %s 超过 %v 仍未结束，停止轮询", pluginName, jobID, cfg.jobMaxWait)
+		onDone(job, true)
+	}()
+}
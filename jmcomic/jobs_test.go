@@ -0,0 +1,184 @@
+package jmcomic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+// jobBackend 模拟的API服务，/jobs/<任务ID> 依次返回设定的状态，用完后重复最后一个
+// 状态为 "missing" 时返回 404 (任务不存在)，为 "unavailable" 时返回 503
+type jobBackend struct {
+	mu     sync.Mutex
+	states map[string][]string
+}
+
+func (b *jobBackend) next(jobID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := b.states[jobID]
+	if len(states) == 0 {
+		return "missing"
+	}
+	if len(states) > 1 {
+		b.states[jobID] = states[1:]
+	}
+	return states[0]
+}
+
+func (b *jobBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/jobs/") {
+		return // /health
+	}
+	jobID := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	switch state := b.next(jobID); state {
+	case "missing":
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"error","code":"not_found","message":"Job not found"}`))
+	case "unavailable":
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status":"error","message":"模拟错误"}`))
+	default:
+		status := JobStatus{JobID: jobID, AlbumID: "350234", State: sdk.JobState(state), Message: "状态 " + state}
+		if status.State == JobStateFailed {
+			status.Error, status.ErrorCode = "下载出错", string(sdk.ErrCodeTimeout)
+		}
+		data, _ := json.Marshal(status)
+		_, _ = w.Write([]byte(`{"status":"success","data":` + string(data) + `}`))
+	}
+}
+
+// withJobBackend 让命令处理器的API指向模拟的服务，并缩短轮询间隔
+func withJobBackend(t *testing.T, states map[string][]string) {
+	t.Helper()
+	srv := httptest.NewServer(&jobBackend{states: states})
+	t.Cleanup(srv.Close)
+	conf := sdk.DefaultConfig()
+	conf.BaseURL = srv.URL
+	conf.HealthCheckInterval = time.Hour
+	conf.MaxRetries = 0
+	conf.DisableClientFallback = true
+	client := sdk.NewClient(conf)
+	t.Cleanup(client.Close)
+
+	withTestConfig(t)
+	cfg.jobPollInterval = 5 * time.Millisecond
+	cfg.jobMaxWait = 5 * time.Second
+	cfg.timeoutDuration = time.Second
+	saved := api
+	api = client
+	t.Cleanup(func() { api = saved })
+}
+
+type watchResult struct {
+	job      DownloadJob
+	timedOut bool
+}
+
+// watchJob 提交一个任务并等待 watch 回调
+func watchJob(t *testing.T, s *jobStore, jobID string) watchResult {
+	t.Helper()
+	s.add(&DownloadJob{JobID: jobID, AlbumID: "350234", SubmittedAt: time.Now(), Status: JobStatus{JobID: jobID, State: JobStateQueued}})
+	done := make(chan watchResult, 1)
+	s.watch(jobID, func(job DownloadJob, timedOut bool) {
+		done <- watchResult{job: job, timedOut: timedOut}
+	})
+	select {
+	case r := <-done:
+		return r
+	case <-time.After(10 * time.Second):
+		t.Fatalf("任务 %s 的 watch 没有结束", jobID)
+		return watchResult{}
+	}
+}
+
+func TestJobWatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		states    []string
+		want      sdk.JobState
+		errorCode string
+	}{
+		{name: "完成", states: []string{"queued", "running", "running", "completed"}, want: JobStateCompleted},
+		{name: "失败", states: []string{"running", "failed"}, want: JobStateFailed, errorCode: string(sdk.ErrCodeTimeout)},
+		{name: "临时错误后完成", states: []string{"running", "unavailable", "completed"}, want: JobStateCompleted},
+		{name: "任务不存在", states: []string{"running", "missing"}, want: JobStateFailed, errorCode: string(sdk.ErrCodeNotFound)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withJobBackend(t, map[string][]string{"job1": tt.states})
+			s := &jobStore{jobs: make(map[string]*DownloadJob)}
+			r := watchJob(t, s, "job1")
+			if r.timedOut {
+				t.Fatal("任务已结束，不应超时")
+			}
+			if r.job.Status.State != tt.want || r.job.Status.ErrorCode != tt.errorCode {
+				t.Fatalf("任务结束时的状态为 %q [%s]，期望 %q [%s]", r.job.Status.State, r.job.Status.ErrorCode, tt.want, tt.errorCode)
+			}
+			if job, _ := s.get("job1"); job.Status.State != tt.want {
+				t.Fatalf("记录中的状态为 %q，期望 %q", job.Status.State, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobWatchTimeout(t *testing.T) {
+	withJobBackend(t, map[string][]string{"job1": {"running"}})
+	cfg.jobMaxWait = 50 * time.Millisecond
+	s := &jobStore{jobs: make(map[string]*DownloadJob)}
+	r := watchJob(t, s, "job1")
+	if !r.timedOut || r.job.Status.State != JobStateRunning {
+		t.Fatalf("watch 结果为 timedOut=%v 状态 %q，期望超时且仍在下载中", r.timedOut, r.job.Status.State)
+	}
+}
+
+func TestJobStoreRefresh(t *testing.T) {
+	withJobBackend(t, map[string][]string{"job1": {"completed"}, "other": {"running"}})
+	s := &jobStore{jobs: make(map[string]*DownloadJob)}
+	s.add(&DownloadJob{JobID: "job1", GroupID: 1001, SubmittedAt: time.Now()})
+
+	job, err := s.refresh(context.Background(), "job1")
+	if err != nil || job.Status.State != JobStateCompleted || job.GroupID != 1001 {
+		t.Fatalf("refresh = %+v, %v，期望更新已有任务的状态", job, err)
+	}
+	// 不是本插件提交的任务只返回查询到的状态，不加入记录
+	job, err = s.refresh(context.Background(), "other")
+	if err != nil || job.Status.State != JobStateRunning || job.AlbumID != "350234" {
+		t.Fatalf("refresh = %+v, %v", job, err)
+	}
+	if _, ok := s.get("other"); ok {
+		t.Fatal("其他任务不应加入记录")
+	}
+	if _, err := s.refresh(context.Background(), "missing"); sdk.CodeOf(err) != sdk.ErrCodeNotFound {
+		t.Fatalf("查询不存在的任务返回 %v，期望 not_found", err)
+	}
+}
+
+func TestJobStorePrune(t *testing.T) {
+	withTestConfig(t)
+	cfg.MaxJobsKept = 2
+	s := &jobStore{jobs: make(map[string]*DownloadJob)}
+	start := time.Now()
+	add := func(jobID string, state sdk.JobState, age time.Duration) {
+		s.add(&DownloadJob{JobID: jobID, SubmittedAt: start.Add(-age), Status: JobStatus{State: state}})
+	}
+	add("running", JobStateRunning, 4*time.Hour)
+	add("old", JobStateCompleted, 3*time.Hour)
+	add("failed", JobStateFailed, 2*time.Hour)
+	add("new", JobStateCompleted, time.Hour)
+	for jobID, want := range map[string]bool{"running": true, "old": false, "failed": false, "new": true} {
+		if _, ok := s.get(jobID); ok != want {
+			t.Errorf("任务 %s 保留为 %v，期望 %v (未结束的任务不清理，已结束的先清理最早的)", jobID, ok, want)
+		}
+	}
+	if got := s.listByChat(0, 0); len(got) != 2 || got[0].JobID != "new" {
+		t.Fatalf("listByChat 返回 %+v，期望按提交时间倒序", got)
+	}
+}
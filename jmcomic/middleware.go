@@ -0,0 +1,227 @@
+package jmcomic
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// invocation 一次子命令调用，中间件通过它传递结果
+type invocation struct {
+	cmd      *command
+	args     []string
+	rejected string // 被中间件拒绝的原因 ("权限"、"频率"、"参数")，未拒绝时为空
+	panicked bool
+}
+
+// commandFunc 处理一次子命令调用
+type commandFunc func(ctx *zero.Ctx, inv *invocation)
+
+// middleware 包装 commandFunc，可以在调用前后做处理或拒绝调用
+type middleware func(next commandFunc) commandFunc
+
+// chainMiddlewares 按顺序包装处理函数，第一个中间件在最外层
+func chainMiddlewares(h commandFunc, middlewares ...middleware) commandFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// recoverMiddleware 捕获处理器中的 panic，记录日志并提示用户，避免影响其他插件
+func recoverMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		defer func() {
+			if r := recover(); r != nil {
+				inv.panicked = true
+				zlog.Errorf("[%s Command] 命令 %s 执行时发生错误: %v\n%s", pluginName, inv.cmd.name, r, debug.Stack())
+				ctx.SendChain(message.Text("命令执行出错，请稍后再试。"))
+			}
+		}()
+		next(ctx, inv)
+	}
+}
+
+// loggingMiddleware 记录每次命令调用的用户、会话、结果和耗时
+func loggingMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		start := time.Now()
+		next(ctx, inv)
+		result := "完成"
+		switch {
+		case inv.panicked:
+			result = "出错"
+		case inv.rejected != "":
+			result = "被拒绝 (" + inv.rejected + ")"
+		}
+		zlog.Infof("[%s Command] 用户 %d (群 %d) 执行 %s %s，用时 %v", pluginName, ctx.Event.UserID, ctx.Event.GroupID,
+			inv.cmd.name, result, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// authMiddleware 检查命令需要的权限
+func authMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		if !inv.cmd.perm.allowed(ctx) {
+			inv.rejected = "权限"
+			ctx.SendChain(message.Text(fmt.Sprintf("%s %s 命令%s可用。", cmdPrefix, inv.cmd.name, inv.cmd.perm.text())))
+			return
+		}
+		next(ctx, inv)
+	}
+}
+
+// argsMiddleware 按命令声明的参数检查参数个数，不符合时回复用法
+func argsMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		if err := inv.cmd.checkArgs(inv.args); err != nil {
+			inv.rejected = "参数"
+			ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
+			return
+		}
+		next(ctx, inv)
+	}
+}
+
+// rateLimits 每个用户在当前时间窗口内执行的命令数
+var rateLimits = struct {
+	sync.Mutex
+	windows map[int64]*rateWindow
+}{windows: make(map[int64]*rateWindow)}
+
+type rateWindow struct {
+	start    time.Time
+	count    int
+	notified bool // 本窗口内是否已经提示过，超限后只提示一次
+}
+
+// allowCommand 记录一次命令调用，超出 command_rate_limit 时返回 false 和剩余等待时间
+// 第二个返回值表示是否需要提示用户
+func allowCommand(userID int64) (bool, bool, time.Duration) {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	now := time.Now()
+	w, ok := rateLimits.windows[userID]
+	if !ok || now.Sub(w.start) >= cfg.commandRateWindow {
+		if len(rateLimits.windows) >= maxRateLimitEntries {
+			pruneRateWindowsLocked(now)
+		}
+		rateLimits.windows[userID] = &rateWindow{start: now, count: 1}
+		return true, false, 0
+	}
+	if w.count < cfg.CommandRateLimit {
+		w.count++
+		return true, false, 0
+	}
+	notify := !w.notified
+	w.notified = true
+	return false, notify, cfg.commandRateWindow - now.Sub(w.start)
+}
+
+// maxRateLimitEntries 记录的用户数超过此值时清理已过期的窗口
+const maxRateLimitEntries = 1024
+
+func pruneRateWindowsLocked(now time.Time) {
+	for userID, w := range rateLimits.windows {
+		if now.Sub(w.start) >= cfg.commandRateWindow {
+			delete(rateLimits.windows, userID)
+		}
+	}
+}
+
+// rateLimitMiddleware 限制每个用户的命令频率，超级用户不受限制
+// command_rate_limit 为0时不限制
+func rateLimitMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		if cfg.CommandRateLimit > 0 && !zero.SuperUserPermission(ctx) {
+			ok, notify, wait := allowCommand(ctx.Event.UserID)
+			if !ok {
+				inv.rejected = "频率"
+				if notify {
+					ctx.SendChain(message.Text(fmt.Sprintf("操作过于频繁，请 %v 后再试。", wait.Round(time.Second))))
+				}
+				return
+			}
+		}
+		next(ctx, inv)
+	}
+}
+
+// commandStats 一个子命令的调用统计
+type commandStats struct {
+	calls    int64
+	rejected int64
+	panics   int64
+	total    time.Duration
+	max      time.Duration
+}
+
+// commandMetrics 按子命令统计调用次数和耗时，jm admin metrics 查看
+var commandMetrics = struct {
+	sync.Mutex
+	stats map[string]*commandStats
+}{stats: make(map[string]*commandStats)}
+
+// metricsMiddleware 统计每个子命令的调用次数、被拒绝和出错的次数以及耗时
+func metricsMiddleware(next commandFunc) commandFunc {
+	return func(ctx *zero.Ctx, inv *invocation) {
+		start := time.Now()
+		next(ctx, inv)
+		elapsed := time.Since(start)
+
+		commandMetrics.Lock()
+		defer commandMetrics.Unlock()
+		st, ok := commandMetrics.stats[inv.cmd.name]
+		if !ok {
+			st = &commandStats{}
+			commandMetrics.stats[inv.cmd.name] = st
+		}
+		st.calls++
+		switch {
+		case inv.panicked:
+			st.panics++
+		case inv.rejected != "":
+			st.rejected++
+		}
+		st.total += elapsed
+		if elapsed > st.max {
+			st.max = elapsed
+		}
+	}
+}
+
+// metricsText 返回命令统计的描述，按调用次数从多到少排列
+func metricsText() string {
+	commandMetrics.Lock()
+	defer commandMetrics.Unlock()
+	if len(commandMetrics.stats) == 0 {
+		return "还没有命令调用记录。"
+	}
+	names := make([]string, 0, len(commandMetrics.stats))
+	for name := range commandMetrics.stats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := commandMetrics.stats[names[i]], commandMetrics.stats[names[j]]
+		if a.calls != b.calls {
+			return a.calls > b.calls
+		}
+		return names[i] < names[j]
+	})
+	var sb strings.Builder
+	sb.WriteString("命令统计 (自插件加载以来):")
+	for _, name := range names {
+		st := commandMetrics.stats[name]
+		avg := st.total / time.Duration(st.calls)
+		sb.WriteString(fmt.Sprintf("\n%s: 调用 %d, 拒绝 %d, 出错 %d, 平均 %v, 最长 %v",
+			name, st.calls, st.rejected, st.panics, avg.Round(time.Millisecond), st.max.Round(time.Millisecond)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package jmcomic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// testCtx 一个普通用户在群中发出命令的上下文
+func testCtx(userID int64) *zero.Ctx {
+	return &zero.Ctx{Event: &zero.Event{UserID: userID, GroupID: 1001}}
+}
+
+// withRateLimit 设置命令频率限制，并在测试结束时恢复设置、清空记录
+func withRateLimit(t *testing.T, limit int, window time.Duration) {
+	t.Helper()
+	savedLimit, savedWindow := cfg.CommandRateLimit, cfg.commandRateWindow
+	cfg.CommandRateLimit, cfg.commandRateWindow = limit, window
+	reset := func() {
+		rateLimits.Lock()
+		rateLimits.windows = make(map[int64]*rateWindow)
+		rateLimits.Unlock()
+	}
+	reset()
+	t.Cleanup(func() {
+		cfg.CommandRateLimit, cfg.commandRateWindow = savedLimit, savedWindow
+		reset()
+	})
+}
+
+// testCommand 返回一个记录调用次数的子命令
+func testCommand(name string, perm permission, args []argSpec, calls *int) *command {
+	return &command{name: name, perm: perm, args: args, handler: func(*zero.Ctx, []string) { *calls++ }}
+}
+
+// takeStats 取出并删除子命令的统计，避免影响其他测试
+func takeStats(name string) commandStats {
+	commandMetrics.Lock()
+	defer commandMetrics.Unlock()
+	st, ok := commandMetrics.stats[name]
+	if !ok {
+		return commandStats{}
+	}
+	delete(commandMetrics.stats, name)
+	return *st
+}
+
+func TestChainMiddlewaresOrder(t *testing.T) {
+	var trace []string
+	record := func(name string) middleware {
+		return func(next commandFunc) commandFunc {
+			return func(ctx *zero.Ctx, inv *invocation) {
+				trace = append(trace, name+" 前")
+				next(ctx, inv)
+				trace = append(trace, name+" 后")
+			}
+		}
+	}
+	h := chainMiddlewares(func(*zero.Ctx, *invocation) { trace = append(trace, "处理器") }, record("a"), record("b"))
+	h(testCtx(1), &invocation{})
+	want := []string{"a 前", "b 前", "处理器", "b 后", "a 后"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("调用顺序为 %q，期望 %q", trace, want)
+	}
+}
+
+func TestRegistryMiddlewareOrder(t *testing.T) {
+	withRateLimit(t, 1, time.Hour)
+	var calls int
+
+	// 权限检查在参数检查之前，无权限时不提示参数错误
+	admin := testCommand("test-admin", permSuperUser, nil, &calls)
+	inv := &invocation{cmd: admin, args: []string{"多余的参数"}}
+	commands.handler(testCtx(1), inv)
+	if inv.rejected != "权限" || calls != 0 {
+		t.Fatalf("无权限调用被拒绝的原因为 %q，处理器调用 %d 次，期望只因权限被拒绝", inv.rejected, calls)
+	}
+	if st := takeStats("test-admin"); st.calls != 1 || st.rejected != 1 {
+		t.Fatalf("统计为 %+v，期望被拒绝的调用也计入统计", st)
+	}
+
+	// 频率限制在参数检查之前，超限后参数错误的调用也按频率拒绝
+	cmd := testCommand("test-args", permEveryone, []argSpec{{name: "漫画ID"}}, &calls)
+	inv = &invocation{cmd: cmd, args: []string{"350234"}}
+	commands.handler(testCtx(2), inv)
+	if inv.rejected != "" || calls != 1 {
+		t.Fatalf("第一次调用被拒绝 (%q)，处理器调用 %d 次", inv.rejected, calls)
+	}
+	inv = &invocation{cmd: cmd}
+	commands.handler(testCtx(2), inv)
+	if inv.rejected != "频率" || calls != 1 {
+		t.Fatalf("超限的调用被拒绝的原因为 %q，期望 \"频率\"", inv.rejected)
+	}
+	// 其他用户不受影响，参数不足时由参数检查拒绝
+	inv = &invocation{cmd: cmd}
+	commands.handler(testCtx(3), inv)
+	if inv.rejected != "参数" || calls != 1 {
+		t.Fatalf("参数不足的调用被拒绝的原因为 %q，期望 \"参数\"", inv.rejected)
+	}
+	if st := takeStats("test-args"); st.calls != 3 || st.rejected != 2 {
+		t.Fatalf("统计为 %+v，期望 3 次调用、2 次被拒绝", st)
+	}
+}
+
+func TestRegistryRecoversPanic(t *testing.T) {
+	withRateLimit(t, 0, time.Minute)
+	cmd := &command{name: "test-panic", handler: func(*zero.Ctx, []string) { panic("测试") }}
+	inv := &invocation{cmd: cmd}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("panic 没有被捕获: %v", r)
+			}
+		}()
+		commands.handler(testCtx(1), inv)
+	}()
+	if !inv.panicked {
+		t.Fatal("处理器 panic 后 invocation.panicked 应为 true")
+	}
+	// 统计在 recover 外层，出错的调用同样计入
+	if st := takeStats("test-panic"); st.calls != 1 || st.panics != 1 {
+		t.Fatalf("统计为 %+v，期望 1 次调用、1 次出错", st)
+	}
+}
+
+func TestAllowCommand(t *testing.T) {
+	withRateLimit(t, 2, 50*time.Millisecond)
+	check := func(step string, wantOK, wantNotify bool) {
+		t.Helper()
+		ok, notify, wait := allowCommand(1)
+		if ok != wantOK || notify != wantNotify {
+			t.Fatalf("%s: allowCommand = %v, %v，期望 %v, %v", step, ok, notify, wantOK, wantNotify)
+		}
+		if !ok && (wait <= 0 || wait > cfg.commandRateWindow) {
+			t.Fatalf("%s: 等待时间 %v 不在窗口内", step, wait)
+		}
+	}
+	check("第一次", true, false)
+	check("第二次", true, false)
+	check("超限", false, true)
+	check("超限后只提示一次", false, false)
+	if ok, _, _ := allowCommand(2); !ok {
+		t.Fatal("其他用户不应受影响")
+	}
+	time.Sleep(60 * time.Millisecond)
+	check("新窗口", true, false)
+}
+
+func TestRegistryLookup(t *testing.T) {
+	for name, want := range map[string]string{"download": "download", "DL": "download", "下载": "download", "s": "search", "Help": "help"} {
+		c, ok := commands.lookup(name)
+		if !ok || c.name != want {
+			t.Errorf("lookup(%q) 找到 %v，期望 %s", name, ok, want)
+		}
+	}
+	if _, ok := commands.lookup("nope"); ok {
+		t.Error("不存在的命令不应找到")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("别名重复时应当 panic")
+		}
+	}()
+	newCommandRegistry([]*command{{name: "a", aliases: []string{"b"}}, {name: "B"}})
+}
+
+func TestCheckArgs(t *testing.T) {
+	c := &command{
+		name:  "download",
+		flags: []flagSpec{{name: "format", value: "pdf"}},
+		args:  []argSpec{{name: "漫画ID"}, {name: "章节", optional: true}},
+	}
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: []string{"350234"}},
+		{args: []string{"350234", "1"}},
+		{args: []string{"--format", "pdf", "350234", "1"}},
+		{args: nil, wantErr: true},
+		{args: []string{"--format", "pdf"}, wantErr: true},
+		{args: []string{"350234", "1", "2"}, wantErr: true},
+		{args: []string{"350234", "--size", "1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := c.checkArgs(tt.args); (err != nil) != tt.wantErr {
+			t.Errorf("checkArgs(%q) = %v，期望出错为 %v", tt.args, err, tt.wantErr)
+		}
+	}
+	c.args[1].variadic = true
+	if err := c.checkArgs([]string{"350234", "1", "2", "3"}); err != nil {
+		t.Errorf("可变参数不应限制个数: %v", err)
+	}
+}
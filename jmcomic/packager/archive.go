@@ -0,0 +1,132 @@
+package packager
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// comicInfo ComicInfo.xml 的内容，被 Komga、Tachiyomi 等阅读器识别
+// 参考: https://anansi-project.github.io/docs/comicinfo/documentation
+type comicInfo struct {
+	XMLName   xml.Name `xml:"ComicInfo"`
+	XMLNSXsi  string   `xml:"xmlns:xsi,attr"`
+	XMLNSXsd  string   `xml:"xmlns:xsd,attr"`
+	Title     string   `xml:"Title,omitempty"`
+	Series    string   `xml:"Series,omitempty"`
+	Summary   string   `xml:"Summary,omitempty"`
+	Writer    string   `xml:"Writer,omitempty"`
+	Tags      string   `xml:"Tags,omitempty"`
+	Web       string   `xml:"Web,omitempty"`
+	PageCount int      `xml:"PageCount"`
+	Manga     string   `xml:"Manga,omitempty"`
+	Notes     string   `xml:"Notes,omitempty"`
+}
+
+// writeCBZ 写入CBZ: 页面按顺序平铺命名为 0001.jpg 等，并附带 ComicInfo.xml
+func writeCBZ(w io.Writer, meta Metadata, pages []Page) error {
+	zw := zip.NewWriter(w)
+
+	info := comicInfo{
+		XMLNSXsi:  "http://www.w3.org/2001/XMLSchema-instance",
+		XMLNSXsd:  "http://www.w3.org/2001/XMLSchema",
+		Title:     meta.Title,
+		Series:    meta.Title,
+		Summary:   meta.Summary,
+		Writer:    meta.Writer,
+		Tags:      meta.Tags,
+		Web:       meta.Web,
+		PageCount: len(pages),
+		Manga:     "YesAndRightToLeft",
+	}
+	if meta.ID != "" {
+		info.Notes = "JM" + meta.ID
+	}
+	infoXML, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("生成 ComicInfo.xml 失败: %w", err)
+	}
+	infoWriter, err := zw.Create("ComicInfo.xml")
+	if err != nil {
+		return fmt.Errorf("写入 ComicInfo.xml 失败: %w", err)
+	}
+	if _, err := io.WriteString(infoWriter, xml.Header); err != nil {
+		return fmt.Errorf("写入 ComicInfo.xml 失败: %w", err)
+	}
+	if _, err := infoWriter.Write(infoXML); err != nil {
+		return fmt.Errorf("写入 ComicInfo.xml 失败: %w", err)
+	}
+
+	for i, page := range pages {
+		name := fmt.Sprintf("%04d%s", i+1, strings.ToLower(filepath.Ext(page.Name)))
+		if err := addFile(zw, name, page.Path); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("写入CBZ失败: %w", err)
+	}
+	return nil
+}
+
+// writeZIP 写入普通ZIP: 每个章节一个目录，保留原始文件名
+func writeZIP(w io.Writer, pages []Page) error {
+	zw := zip.NewWriter(w)
+	for _, page := range pages {
+		dir := page.ChapterIndex
+		if page.ChapterTitle != "" {
+			dir += "_" + page.ChapterTitle
+		}
+		name := path.Join(safeName(dir), safeName(page.Name))
+		if err := addFile(zw, name, page.Path); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("写入ZIP失败: %w", err)
+	}
+	return nil
+}
+
+// addFile 以不压缩的方式写入一个文件 (图片本身已是压缩格式)
+func addFile(zw *zip.Writer, name, localPath string) error {
+	in, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("读取图片 %s 失败: %w", localPath, err)
+	}
+	defer in.Close()
+
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	if _, err := io.Copy(fw, in); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// safeName 去除归档内路径中不安全的字符
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Trim(s, ". ")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
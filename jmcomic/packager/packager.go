@@ -0,0 +1,152 @@
+// Package packager 将下载得到的漫画图片打包为单个 PDF、CBZ 或 ZIP 文件
+// 该包不依赖插件的其他部分，可以在处理器之外单独使用
+package packager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format 打包格式
+type Format string
+
+const (
+	FormatPDF Format = "pdf" // 每张图片一页的PDF
+	FormatCBZ Format = "cbz" // 带 ComicInfo.xml 的漫画归档
+	FormatZIP Format = "zip" // 按章节分目录的普通ZIP
+)
+
+// Formats 所有支持的格式，用于生成用法提示
+var Formats = []Format{FormatPDF, FormatCBZ, FormatZIP}
+
+// ParseFormat 解析格式名称 (不区分大小写)
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的格式 '%s'，可选: pdf, cbz, zip", s)
+}
+
+// Ext 返回格式对应的文件扩展名 (含点)
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Metadata 写入归档的漫画元数据
+type Metadata struct {
+	ID      string
+	Title   string
+	Writer  string
+	Tags    string // 逗号分隔
+	Summary string
+	Web     string
+}
+
+// Page 待打包的单张图片
+type Page struct {
+	ChapterID    string
+	ChapterIndex string // 章节序号，用于排序
+	ChapterTitle string
+	Name         string // 原始文件名，用于章节内排序
+	Path         string // 本地文件路径
+}
+
+// SortPages 按章节序号、再按章节内文件名对页面排序
+// 序号和文件名中的数字按数值比较，因此 "10" 排在 "9" 之后
+func SortPages(pages []Page) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		a, b := pages[i], pages[j]
+		if a.ChapterIndex != b.ChapterIndex {
+			return naturalLess(a.ChapterIndex, b.ChapterIndex)
+		}
+		if a.ChapterID != b.ChapterID {
+			return naturalLess(a.ChapterID, b.ChapterID)
+		}
+		return naturalLess(a.Name, b.Name)
+	})
+}
+
+// Pack 将页面按顺序打包写入 w
+func Pack(format Format, meta Metadata, pages []Page, w io.Writer) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("没有可打包的页面")
+	}
+	sorted := make([]Page, len(pages))
+	copy(sorted, pages)
+	SortPages(sorted)
+
+	switch format {
+	case FormatPDF:
+		return writePDF(w, meta, sorted)
+	case FormatCBZ:
+		return writeCBZ(w, meta, sorted)
+	case FormatZIP:
+		return writeZIP(w, sorted)
+	default:
+		return fmt.Errorf("不支持的格式 '%s'", format)
+	}
+}
+
+// PackFile 将页面打包写入本地文件，失败时删除不完整的文件
+func PackFile(format Format, meta Metadata, pages []Page, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败: %w", err)
+	}
+	err = Pack(format, meta, pages, out)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// naturalLess 比较两个字符串，其中连续的数字按数值大小比较
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		ra, rb := []rune(a), []rune(b)
+		if isDigit(ra[0]) && isDigit(rb[0]) {
+			na, restA := splitNumber(a)
+			nb, restB := splitNumber(b)
+			if na != nb {
+				return na < nb
+			}
+			a, b = restA, restB
+			continue
+		}
+		if ra[0] != rb[0] {
+			return ra[0] < rb[0]
+		}
+		a, b = string(ra[1:]), string(rb[1:])
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// splitNumber 拆出字符串开头的数字部分
+func splitNumber(s string) (uint64, string) {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, err := strconv.ParseUint(s[:end], 10, 64)
+	if err != nil {
+		// 数字过长时退化为按长度比较
+		n = uint64(end) << 56
+	}
+	return n, s[end:]
+}
@@ -0,0 +1,189 @@
+package packager
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"9.jpg", "10.jpg", true},
+		{"00001.webp", "00002.webp", true},
+		{"page2", "page10", true},
+		{"a", "b", true},
+		{"b", "a", false},
+		{"a", "a", false},
+		{"a", "a1", true},
+		{"a1", "a", false},
+		{"第2话", "第10话", true},
+		{"", "a", true},
+		{"a", "", false},
+		{"99999999999999999999999", "1", false},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %v, 期望 %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortPages(t *testing.T) {
+	pages := []Page{
+		{ChapterID: "300", ChapterIndex: "10", Name: "1.jpg"},
+		{ChapterID: "200", ChapterIndex: "2", Name: "10.jpg"},
+		{ChapterID: "200", ChapterIndex: "2", Name: "9.jpg"},
+		{ChapterID: "100", ChapterIndex: "1", Name: "2.jpg"},
+		{ChapterID: "100", ChapterIndex: "1", Name: "1.jpg"},
+	}
+	SortPages(pages)
+	var got []string
+	for _, p := range pages {
+		got = append(got, p.ChapterIndex+"/"+p.Name)
+	}
+	want := []string{"1/1.jpg", "1/2.jpg", "2/9.jpg", "2/10.jpg", "10/1.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SortPages 顺序为 %v，期望 %v", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"pdf", "CBZ", " zip "} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) 失败: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("rar"); err == nil {
+		t.Error("ParseFormat(\"rar\") 应当失败")
+	}
+}
+
+// writePages 在临时目录中生成测试用的PNG图片，页面顺序故意打乱
+func writePages(t *testing.T) []Page {
+	t.Helper()
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 6))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	specs := []Page{
+		{ChapterID: "201", ChapterIndex: "2", ChapterTitle: "第2话", Name: "1.png"},
+		{ChapterID: "200", ChapterIndex: "1", ChapterTitle: "第1话", Name: "10.png"},
+		{ChapterID: "200", ChapterIndex: "1", ChapterTitle: "第1话", Name: "2.png"},
+	}
+	for i := range specs {
+		specs[i].Path = filepath.Join(dir, specs[i].ChapterID+"_"+specs[i].Name)
+		if err := os.WriteFile(specs[i].Path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return specs
+}
+
+// readZip 返回归档中的条目名和 ComicInfo.xml 的内容
+func readZip(t *testing.T, data []byte) ([]string, []byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("读取归档失败: %v", err)
+	}
+	var names []string
+	var info []byte
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "ComicInfo.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	return names, info
+}
+
+func TestPackCBZ(t *testing.T) {
+	pages := writePages(t)
+	meta := Metadata{ID: "350234", Title: "示例 & <标题>", Writer: "作者", Tags: "a, b", Summary: "简介"}
+	var buf bytes.Buffer
+	if err := Pack(FormatCBZ, meta, pages, &buf); err != nil {
+		t.Fatalf("Pack 失败: %v", err)
+	}
+	names, infoXML := readZip(t, buf.Bytes())
+	want := []string{"ComicInfo.xml", "0001.png", "0002.png", "0003.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("CBZ 条目为 %v，期望 %v", names, want)
+	}
+	var info comicInfo
+	if err := xml.Unmarshal(infoXML, &info); err != nil {
+		t.Fatalf("解析 ComicInfo.xml 失败: %v", err)
+	}
+	if info.Title != meta.Title || info.Writer != meta.Writer || info.PageCount != 3 || info.Notes != "JM350234" {
+		t.Fatalf("ComicInfo.xml 内容不正确: %+v", info)
+	}
+}
+
+func TestPackZIP(t *testing.T) {
+	pages := writePages(t)
+	var buf bytes.Buffer
+	if err := Pack(FormatZIP, Metadata{}, pages, &buf); err != nil {
+		t.Fatalf("Pack 失败: %v", err)
+	}
+	names, info := readZip(t, buf.Bytes())
+	want := []string{"1_第1话/2.png", "1_第1话/10.png", "2_第2话/1.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("ZIP 条目为 %v，期望 %v", names, want)
+	}
+	if info != nil {
+		t.Fatal("普通ZIP不应包含 ComicInfo.xml")
+	}
+}
+
+func TestPackPDF(t *testing.T) {
+	pages := writePages(t)
+	out := filepath.Join(t.TempDir(), "out.pdf")
+	if err := PackFile(FormatPDF, Metadata{Title: "示例"}, pages, out); err != nil {
+		t.Fatalf("PackFile 失败: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.Contains(data, []byte("%%EOF")) {
+		t.Fatal("输出不是完整的PDF文件")
+	}
+	if n := strings.Count(string(data), "/Type /Page "); n != len(pages) {
+		t.Fatalf("PDF 页数为 %d，期望 %d", n, len(pages))
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	if err := Pack(FormatZIP, Metadata{}, nil, io.Discard); err == nil {
+		t.Error("没有页面时应当失败")
+	}
+	out := filepath.Join(t.TempDir(), "missing.cbz")
+	err := PackFile(FormatCBZ, Metadata{}, []Page{{Name: "1.jpg", Path: filepath.Join(t.TempDir(), "missing.jpg")}}, out)
+	if err == nil {
+		t.Fatal("图片不存在时应当失败")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("失败时应删除不完整的输出文件")
+	}
+}
@@ -0,0 +1,164 @@
+package packager
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	// 注册常见图片格式的解码器，非JPEG图片会被转码为JPEG后写入PDF
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// countingWriter 记录已写入的字节数，用于生成PDF交叉引用表
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...interface{}) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
+
+func (c *countingWriter) write(p []byte) {
+	if c.err != nil {
+		return
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+}
+
+// pdfImage 可直接嵌入PDF的JPEG图片
+type pdfImage struct {
+	data       []byte
+	width      int
+	height     int
+	colorSpace string
+}
+
+// loadPDFImage 读取图片文件；JPEG原样嵌入，其他格式解码后转码为JPEG
+func loadPDFImage(path string) (*pdfImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片 %s 失败: %w", path, err)
+	}
+	imgCfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("识别图片 %s 失败: %w", path, err)
+	}
+
+	if format == "jpeg" {
+		colorSpace := "/DeviceRGB"
+		switch imgCfg.ColorModel {
+		case color.GrayModel:
+			colorSpace = "/DeviceGray"
+		case color.CMYKModel:
+			colorSpace = "/DeviceCMYK"
+		}
+		return &pdfImage{data: data, width: imgCfg.Width, height: imgCfg.Height, colorSpace: colorSpace}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码图片 %s 失败: %w", path, err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("转码图片 %s 失败: %w", path, err)
+	}
+	bounds := img.Bounds()
+	return &pdfImage{data: buf.Bytes(), width: bounds.Dx(), height: bounds.Dy(), colorSpace: "/DeviceRGB"}, nil
+}
+
+// writePDF 生成每张图片占一页的PDF，页面尺寸与图片像素尺寸一致
+// 对象编号: 1 Catalog, 2 Pages, 3 Info, 之后每页依次为 Page, Contents, Image 三个对象
+func writePDF(w io.Writer, meta Metadata, pages []Page) error {
+	cw := &countingWriter{w: w}
+	objCount := 3 + 3*len(pages)
+	offsets := make([]int64, objCount+1)
+
+	beginObj := func(num int) {
+		offsets[num] = cw.n
+		cw.printf("%d 0 obj\n", num)
+	}
+
+	cw.printf("%%PDF-1.4\n%%\xE2\xE3\xCF\xD3\n")
+
+	beginObj(1)
+	cw.printf("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+3*i)
+	}
+	beginObj(2)
+	cw.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pages))
+
+	beginObj(3)
+	cw.printf("<< /Title %s /Author %s /Subject %s /Keywords %s /Producer %s >>\nendobj\n",
+		pdfString(meta.Title), pdfString(meta.Writer), pdfString(meta.Summary), pdfString(meta.Tags), pdfString("jmcomic packager"))
+
+	for i, page := range pages {
+		img, err := loadPDFImage(page.Path)
+		if err != nil {
+			return err
+		}
+		pageObj, contentObj, imageObj := 4+3*i, 5+3*i, 6+3*i
+
+		beginObj(pageObj)
+		cw.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im%d %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			img.width, img.height, i, imageObj, contentObj)
+
+		content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im%d Do Q", img.width, img.height, i)
+		beginObj(contentObj)
+		cw.printf("<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+
+		beginObj(imageObj)
+		cw.printf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+			img.width, img.height, img.colorSpace, len(img.data))
+		cw.write(img.data)
+		cw.printf("\nendstream\nendobj\n")
+
+		if cw.err != nil {
+			return fmt.Errorf("写入PDF失败: %w", cw.err)
+		}
+	}
+
+	xrefOffset := cw.n
+	cw.printf("xref\n0 %d\n0000000000 65535 f \n", objCount+1)
+	for num := 1; num <= objCount; num++ {
+		cw.printf("%010d 00000 n \n", offsets[num])
+	}
+	cw.printf("trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", objCount+1, xrefOffset)
+
+	if cw.err != nil {
+		return fmt.Errorf("写入PDF失败: %w", cw.err)
+	}
+	return nil
+}
+
+// pdfString 将文本编码为带BOM的UTF-16BE十六进制字符串，以支持中文等非ASCII字符
+func pdfString(s string) string {
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
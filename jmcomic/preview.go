@@ -0,0 +1,128 @@
+package jmcomic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// handlePreview 处理章节预览命令: jm preview <漫画ID> [章节] [页数]
+// 章节的写法与 jm download 相同 (序号、latest、id:章节ID 或章节链接)，但只能选择一个章节
+// 参数个数已由命令注册表检查
+// 预览图片以合并转发消息发送，避免在群聊中刷屏
+func handlePreview(ctx *zero.Ctx, args []string) {
+	var selection chapterArgs
+	if len(args) > 1 {
+		var err error
+		if selection, err = parseChapterArgs(args[1:2]); err != nil {
+			ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
+			return
+		}
+	}
+	count := cfg.PreviewPages
+	if len(args) > 2 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n <= 0 {
+			ctx.SendChain(message.Text(fmt.Sprintf("页数 '%s' 无效，请输入正整数 (最多 %d)。", sanitizeText(args[2], maxShortRunes), cfg.MaxPreviewPages)))
+			return
+		}
+		count = n
+	}
+	if count > cfg.MaxPreviewPages {
+		count = cfg.MaxPreviewPages
+	}
+	ref, ok := resolveArg(ctx, args[0])
+	if !ok {
+		return
+	}
+	albumID := string(ref.AlbumID)
+	if selection.empty() && ref.PhotoID != "" { // 发送章节链接时预览该章节
+		selection.ids = append(selection.ids, ref.PhotoID)
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	defer cancel()
+
+	detail, err := api.Detail(reqCtx, albumID)
+	if err != nil {
+		zlog.Errorf("[%s Handler] 预览时获取详情 '%s' 失败: %v", pluginName, albumID, err)
+		ctx.SendChain(message.Text(renderError("获取详情失败", err)))
+		return
+	}
+	if len(detail.Chapters) == 0 {
+		ctx.SendChain(message.Text(fmt.Sprintf("漫画 %s 没有可预览的章节。", albumID)))
+		return
+	}
+
+	chapter, err := previewChapter(detail.Chapters, selection)
+	if err != nil {
+		ctx.SendChain(message.Text(fmt.Sprintf("%s\n使用 %s detail %s 查看章节列表。", sanitizeBlock(err.Error(), 0), cmdPrefix, albumID)))
+		return
+	}
+	if chapter.PageCount > 0 && count > chapter.PageCount {
+		count = chapter.PageCount
+	}
+
+	ctx.SendChain(message.Text(escapeCQ(fmt.Sprintf("正在获取 %s 第 %s 章 (%s) 的前 %d 页...", detail.Title, chapter.Index, chapter.Title, count))))
+
+	pages, err := api.ChapterPages(reqCtx, chapter.ID, count)
+	if err != nil {
+		zlog.Errorf("[%s Handler] 获取章节 '%s' 页面失败: %v", pluginName, chapter.ID, err)
+		ctx.SendChain(message.Text(renderError("获取章节页面失败", err)))
+		return
+	}
+
+	nickname := fmt.Sprintf("JM%s", detail.ID)
+	nodes := message.Message{
+		message.CustomNode(nickname, ctx.Event.SelfID, previewHeader(detail, chapter)),
+	}
+	for _, page := range pages {
+		// 图片较大时单张下载也可能较慢，每张图片使用独立的超时
+		imgCtx, imgCancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+		data, err := api.PageImage(imgCtx, chapter.ID, page.Index, cfg.maxUploadBytes)
+		imgCancel()
+		if err != nil {
+			zlog.Warnf("[%s Handler] 获取章节 '%s' 第 %d 页失败: %v", pluginName, chapter.ID, page.Index+1, err)
+			nodes = append(nodes, message.CustomNode(nickname, ctx.Event.SelfID, fmt.Sprintf("第 %d 页获取失败", page.Index+1)))
+			continue
+		}
+		nodes = append(nodes, message.CustomNode(nickname, ctx.Event.SelfID, message.Message{message.ImageBytes(data)}))
+	}
+	if len(nodes) == 1 {
+		ctx.SendChain(message.Text("未获取到任何页面。"))
+		return
+	}
+
+	if ctx.Event.GroupID != 0 {
+		ctx.SendGroupForwardMessage(ctx.Event.GroupID, nodes)
+	} else {
+		ctx.SendPrivateForwardMessage(ctx.Event.UserID, nodes)
+	}
+}
+
+// previewChapter 按与下载相同的章节选择得到要预览的章节，未指定时为第一个章节
+func previewChapter(chapters []ChapterInfo, selection chapterArgs) (ChapterInfo, error) {
+	if selection.empty() {
+		return orderedChapters(chapters)[0], nil
+	}
+	selected, err := selection.resolve(chapters)
+	if err != nil {
+		return ChapterInfo{}, err
+	}
+	if len(selected) != 1 {
+		return ChapterInfo{}, fmt.Errorf("预览只能选择一个章节，当前选择了 %d 个", len(selected))
+	}
+	if chapterPosition(chapters, selected[0].ID) < 0 {
+		return ChapterInfo{}, fmt.Errorf("该漫画中没有章节 %s", selected[0].ID)
+	}
+	return selected[0], nil
+}
+
+// previewHeader 预览转发消息的第一个节点，字符串内容会被解析为CQ码，需要转义
+func previewHeader(detail *ComicDetail, chapter ChapterInfo) string {
+	return escapeCQ(fmt.Sprintf("%s\n第 %s 章: %s (共 %d 页)", detail.Title, chapter.Index, chapter.Title, chapter.PageCount))
+}
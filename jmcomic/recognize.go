@@ -0,0 +1,172 @@
+package jmcomic
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// recognizeRegex 在聊天消息中查找 "jm350234"、"JM 422866" 或单独的数字
+var recognizeRegex = regexp.MustCompile(`(?i)(jm)?\s*(\d+)`)
+
+// recognizedIDKey 自动识别规则找到的ID在 ctx.State 中的键
+const recognizedIDKey = "jmcomic_recognized_id"
+
+// recognizeCooldowns 记录每个群最近一次自动回复的时间
+var recognizeCooldowns = struct {
+	sync.Mutex
+	last map[int64]time.Time
+}{last: make(map[int64]time.Time)}
+
+// acquireRecognizeCooldown 冷却时间已过时记录本次回复并返回 true
+func acquireRecognizeCooldown(groupID int64) bool {
+	recognizeCooldowns.Lock()
+	defer recognizeCooldowns.Unlock()
+	now := time.Now()
+	if last, ok := recognizeCooldowns.last[groupID]; ok && now.Sub(last) < cfg.recognizeCooldown {
+		return false
+	}
+	recognizeCooldowns.last[groupID] = now
+	return true
+}
+
+// resetRecognizeCooldown 清除群的冷却，查询失败没有回复时调用
+func resetRecognizeCooldown(groupID int64) {
+	recognizeCooldowns.Lock()
+	defer recognizeCooldowns.Unlock()
+	delete(recognizeCooldowns.last, groupID)
+}
+
+// findAlbumID 从消息文本中找出第一个符合识别规则的漫画ID
+func findAlbumID(text string) (string, bool) {
+	for _, m := range recognizeRegex.FindAllStringSubmatchIndex(text, -1) {
+		hasPrefix := m[2] >= 0
+		if cfg.AutoRecognizeRequirePrefix && !hasPrefix {
+			continue
+		}
+		start, digitsStart, end := m[0], m[4], m[5]
+		if !hasPrefix {
+			start = digitsStart
+		}
+		if !standaloneAt(text, start, end) {
+			continue
+		}
+		id := text[digitsStart:end]
+		if len(id) < cfg.AutoRecognizeMinDigits || len(id) > cfg.AutoRecognizeMaxDigits {
+			continue
+		}
+		if ignoredByRules(id) {
+			continue
+		}
+		return id, true
+	}
+	return "", false
+}
+
+// standaloneAt 判断 text[start:end] 是否是独立的一段，而不是单词、日期、时间或小数的一部分
+func standaloneAt(text string, start, end int) bool {
+	if start > 0 {
+		prev := text[start-1]
+		if isASCIIAlnum(prev) {
+			return false
+		}
+		if isNumberSeparator(prev) && start > 1 && isDigit(text[start-2]) {
+			return false
+		}
+	}
+	if end < len(text) {
+		next := text[end]
+		if isASCIIAlnum(next) {
+			return false
+		}
+		if isNumberSeparator(next) && end+1 < len(text) && isDigit(text[end+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoredByRules 判断ID是否匹配配置的过滤规则 (日期、手机号等)
+func ignoredByRules(id string) bool {
+	for _, re := range cfg.recognizeIgnore {
+		if re.MatchString(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isASCIIAlnum(b byte) bool {
+	return isDigit(b) || (b|0x20 >= 'a' && b|0x20 <= 'z')
+}
+
+func isNumberSeparator(b byte) bool {
+	return b == '-' || b == '/' || b == '.' || b == ':'
+}
+
+// isCommandText 判断消息是否为插件命令：命令前缀加 "jm"，后面是空白或消息结束
+// "jm350234"、"jmcomic 350234" 不是命令，交给自动识别处理
+func isCommandText(text string) bool {
+	prefix := zero.BotConfig.CommandPrefix + cmdPrefix
+	if len(text) < len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+		return false
+	}
+	r, size := utf8.DecodeRuneInString(text[len(prefix):])
+	return size == 0 || unicode.IsSpace(r)
+}
+
+// commandRule OnCommand 只按前缀匹配，额外要求消息符合 isCommandText
+func commandRule(ctx *zero.Ctx) bool {
+	return isCommandText(strings.TrimSpace(ctx.Event.GetMessage().ExtractPlainText()))
+}
+
+// autoRecognizeRule 只在开启了自动识别的群中匹配包含漫画ID的普通消息
+// 找到的ID保存在 ctx.State[recognizedIDKey] 中
+func autoRecognizeRule(ctx *zero.Ctx) bool {
+	if !cfg.recognizeGroups[ctx.Event.GroupID] {
+		return false
+	}
+	text := strings.TrimSpace(ctx.Event.GetMessage().ExtractPlainText())
+	if text == "" || isCommandText(text) {
+		return false // 命令由 handleGenericCommand 处理
+	}
+	id, ok := findAlbumID(text)
+	if !ok {
+		return false
+	}
+	ctx.State[recognizedIDKey] = id
+	return true
+}
+
+// handleAutoRecognize 回复识别到的漫画的标题、作者和标签
+// 查询失败时只记录日志，不在群里提示，避免打扰正常聊天
+func handleAutoRecognize(ctx *zero.Ctx) {
+	albumID, _ := ctx.State[recognizedIDKey].(string)
+	groupID := ctx.Event.GroupID
+	if albumID == "" || !acquireRecognizeCooldown(groupID) {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	defer cancel()
+	detail, err := api.Detail(reqCtx, albumID)
+	if err != nil {
+		zlog.Debugf("[%s Recognize] 群 %d 中识别到的ID %s 查询失败: %v", pluginName, groupID, albumID, err)
+		resetRecognizeCooldown(groupID)
+		return
+	}
+	zlog.Infof("[%s Recognize] 群 %d 中识别到漫画 %s", pluginName, groupID, albumID)
+	ctx.SendChain(message.Text(renderText(tmplRecognize, detail)))
+}
@@ -0,0 +1,135 @@
+package jmcomic
+
+import (
+	"testing"
+	"time"
+
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+func TestFindAlbumID(t *testing.T) {
+	tests := []struct {
+		text          string
+		requirePrefix bool
+		want          string // 为空表示不应识别
+	}{
+		{"看看 jm350234", true, "350234"},
+		{"JM 422866 好看", true, "422866"},
+		{"JM123456", true, "123456"},
+		{"jm350234和jm422866", true, "350234"},
+		{"推荐350234", true, ""},
+		{"推荐 350234", false, "350234"},
+		{"abcjm350234", true, ""},  // 单词的一部分
+		{"jm350234abc", true, ""},  // 后面紧跟字母
+		{"id350234", false, ""},    // 前面紧跟字母
+		{"2023-350234", false, ""}, // 日期的一部分
+		{"12:30 到 350234", false, "350234"},
+		{"版本 1.350234", false, ""},            // 小数的一部分
+		{"350234.", false, "350234"},          // 句末的点不算小数
+		{"jm12", true, ""},                    // 少于最少位数
+		{"jm12345678", true, ""},              // 超过最多位数
+		{"jm20230115", true, ""},              // 超过位数，同时也是日期
+		{"jm202301", true, ""},                // 匹配日期过滤规则
+		{"jm 13800138000", true, ""},          // 手机号
+		{"jm202312 jm350234", true, "350234"}, // 跳过被过滤的ID继续查找
+		{"", true, ""},
+	}
+	withTestConfig(t)
+	for _, tt := range tests {
+		cfg.AutoRecognizeRequirePrefix = tt.requirePrefix
+		cfg.AutoRecognizeMinDigits, cfg.AutoRecognizeMaxDigits = 3, 7
+		cfg.AutoRecognizeIgnorePatterns = []string{`^(19|20)\d{2}(0[1-9]|1[0-2])([0-2]\d|3[01])?$`, `^1[3-9]\d{9}$`}
+		cfg.normalize()
+		got, ok := findAlbumID(tt.text)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("findAlbumID(%q) (需要前缀: %v) = %q, %v，期望 %q", tt.text, tt.requirePrefix, got, ok, tt.want)
+		}
+	}
+}
+
+func TestStandaloneAt(t *testing.T) {
+	tests := []struct {
+		text       string
+		start, end int
+		want       bool
+	}{
+		{"123", 0, 3, true},
+		{"a123", 1, 4, false},
+		{"123a", 0, 3, false},
+		{"中123文", 3, 6, true},
+		{"1-123", 2, 5, false},
+		{"-123", 1, 4, true},
+		{"123/4", 0, 3, false},
+		{"123/", 0, 3, true},
+		{"1.5 123", 4, 7, true},
+		{"(123)", 1, 4, true},
+	}
+	for _, tt := range tests {
+		if got := standaloneAt(tt.text, tt.start, tt.end); got != tt.want {
+			t.Errorf("standaloneAt(%q, %d, %d) = %v，期望 %v", tt.text, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestIsCommandText(t *testing.T) {
+	tests := []struct {
+		prefix string
+		text   string
+		want   bool
+	}{
+		{"/", "/jm search 关键词", true},
+		{"/", "/JM 350234", true},
+		{"/", "/jm", true},
+		{"/", "/jm123", false},
+		{"/", "/jm\u3000123", true},
+		{"/", "jm123", false},
+		{"/", "jm 123", false},
+		{"/", "看看 /jm123", false},
+		{"", "jm search 关键词", true},
+		{"", "jm", true},
+		{"", "JM", true},
+		{"", "jm\t350234", true},
+		{"", "jm350234", false},
+		{"", "JM123456", false},
+		{"", "jmcomic 350234", false},
+		{"", "jm中文", false},
+		{"", "看看 jm350234", false},
+		{"", "j", false},
+	}
+	saved := zero.BotConfig.CommandPrefix
+	defer func() { zero.BotConfig.CommandPrefix = saved }()
+	for _, tt := range tests {
+		zero.BotConfig.CommandPrefix = tt.prefix
+		if got := isCommandText(tt.text); got != tt.want {
+			t.Errorf("命令前缀为 %q 时 isCommandText(%q) = %v，期望 %v", tt.prefix, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRecognizeCooldown(t *testing.T) {
+	withTestConfig(t)
+	const group, other = 1001, 1002
+	t.Cleanup(func() {
+		resetRecognizeCooldown(group)
+		resetRecognizeCooldown(other)
+	})
+
+	cfg.recognizeCooldown = time.Hour
+	if !acquireRecognizeCooldown(group) {
+		t.Fatal("第一次识别应当回复")
+	}
+	if acquireRecognizeCooldown(group) {
+		t.Fatal("冷却时间内不应再次回复")
+	}
+	if !acquireRecognizeCooldown(other) {
+		t.Fatal("冷却时间按群分别计算")
+	}
+	resetRecognizeCooldown(group)
+	if !acquireRecognizeCooldown(group) {
+		t.Fatal("清除冷却后应当回复")
+	}
+	cfg.recognizeCooldown = 0
+	if !acquireRecognizeCooldown(group) {
+		t.Fatal("冷却时间为0时每次都应回复")
+	}
+}
@@ -0,0 +1,179 @@
+package jmcomic
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// idRef 用户输入的漫画或章节，可以是ID、jm前缀的ID或JM站点的链接
+type idRef struct {
+	AlbumID sdk.AlbumID // 漫画 (album) ID，输入章节链接时由 resolveIDRef 填入
+	PhotoID sdk.PhotoID // 输入的是章节 (photo) 链接时不为空
+	bare    bool        // 输入的是不带前缀的纯数字，找不到漫画时会再尝试作为章节ID
+}
+
+// refTrimChars 粘贴链接时常见的包裹字符和标点
+const refTrimChars = "<>()[]{}\"'`,，。、；;！!？?"
+
+// parseIDRef 解析用户输入的漫画ID或链接，不请求API服务
+// 支持 350234、jm350234、JM350234 以及 https://18comic.vip/album/350234/...、.../photo/350234 这样的链接
+// 格式不正确时返回可以直接展示给用户的错误
+func parseIDRef(s string) (idRef, error) {
+	s = strings.Trim(strings.TrimSpace(s), refTrimChars)
+	if s == "" {
+		return idRef{}, fmt.Errorf("请输入漫画ID或链接")
+	}
+	if looksLikeURL(s) {
+		return parseIDURL(s)
+	}
+	id, err := sdk.ParseAlbumID(s)
+	if err != nil {
+		return idRef{}, err
+	}
+	return idRef{AlbumID: id, bare: isAllDigits(s)}, nil
+}
+
+// looksLikeURL 判断输入是否是链接: 带协议，或者第一段是 "18comic.vip" 这样的域名
+func looksLikeURL(s string) bool {
+	if strings.Contains(s, "://") {
+		return true
+	}
+	host, _, found := strings.Cut(s, "/")
+	return found && strings.Contains(strings.Trim(host, "."), ".") && !strings.HasPrefix(host, ".")
+}
+
+// parseIDURL 从JM站点的漫画或章节页面链接中取出ID，不带协议的链接按 https 处理
+func parseIDURL(s string) (idRef, error) {
+	raw := s
+	if !strings.Contains(s, "://") {
+		raw = "https://" + s
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return idRef{}, fmt.Errorf("无法识别的链接 '%s'", s)
+	}
+	if !isJMHost(u.Hostname()) {
+		return idRef{}, fmt.Errorf("不支持的链接域名 '%s'，请发送JM的漫画或章节链接", u.Hostname())
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		switch strings.ToLower(segments[i]) {
+		case "album":
+			id, err := sdk.ParseAlbumID(segments[i+1])
+			if err != nil {
+				return idRef{}, err
+			}
+			return idRef{AlbumID: id}, nil
+		case "photo":
+			id, err := sdk.ParsePhotoID(segments[i+1])
+			if err != nil {
+				return idRef{}, err
+			}
+			return idRef{PhotoID: id}, nil
+		}
+	}
+	return idRef{}, fmt.Errorf("链接 '%s' 不是漫画或章节页面", s)
+}
+
+// isJMHost 判断域名是否属于JM: 配置的域名及其子域名，或包含 18comic、jmcomic 的镜像域名
+func isJMHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range cfg.JMDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return strings.Contains(host, "18comic") || strings.Contains(host, "jmcomic")
+}
+
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveIDRef 通过API服务补全 idRef: 章节链接找到所属漫画；纯数字找不到漫画时尝试作为章节ID
+// 漫画详情会经过缓存，随后获取详情的命令不会重复请求
+func resolveIDRef(ctx context.Context, ref idRef) (idRef, error) {
+	if ref.bare {
+		_, err := api.Detail(ctx, string(ref.AlbumID))
+		if err == nil || sdk.CodeOf(err) != sdk.ErrCodeNotFound {
+			// 其他错误交给随后的请求处理和提示
+			return ref, nil
+		}
+		photo, photoErr := api.Photo(ctx, string(ref.AlbumID))
+		if photoErr != nil {
+			return ref, err
+		}
+		return photoRef(photo)
+	}
+	if ref.PhotoID == "" || ref.AlbumID != "" {
+		return ref, nil
+	}
+	photo, err := api.Photo(ctx, string(ref.PhotoID))
+	if err != nil {
+		return ref, err
+	}
+	return photoRef(photo)
+}
+
+// photoRef 由API服务返回的章节信息得到 idRef，返回的ID同样需要校验
+func photoRef(photo *PhotoInfo) (idRef, error) {
+	albumID, err := sdk.ParseAlbumID(photo.AlbumID)
+	if err != nil {
+		return idRef{}, fmt.Errorf("API服务返回的章节 %s 所属漫画无效: %w", photo.ID, err)
+	}
+	photoID, err := sdk.ParsePhotoID(photo.ID)
+	if err != nil {
+		return idRef{}, fmt.Errorf("API服务返回的章节无效: %w", err)
+	}
+	zlog.Debugf("[%s Resolve] 章节 %s 属于漫画 %s", pluginName, photoID, albumID)
+	return idRef{AlbumID: albumID, PhotoID: photoID}, nil
+}
+
+// resolveArg 解析命令中的漫画ID或链接，失败时直接回复用户
+func resolveArg(ctx *zero.Ctx, arg string) (idRef, bool) {
+	ref, err := parseIDRef(arg)
+	if err != nil {
+		ctx.SendChain(message.Text(sanitizeBlock(err.Error(), 0)))
+		return idRef{}, false
+	}
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	defer cancel()
+	ref, err = resolveIDRef(reqCtx, ref)
+	if err != nil {
+		zlog.Errorf("[%s Handler] 解析 '%s' 失败: %v", pluginName, arg, err)
+		ctx.SendChain(message.Text(renderError("解析ID失败", err)))
+		return idRef{}, false
+	}
+	return ref, true
+}
+
+// parseChapterID 解析下载命令中的章节ID，支持纯数字和章节链接
+func parseChapterID(s string) (sdk.PhotoID, error) {
+	s = strings.Trim(strings.TrimSpace(s), refTrimChars)
+	if !looksLikeURL(s) {
+		return sdk.ParsePhotoID(s)
+	}
+	ref, err := parseIDURL(s)
+	if err != nil {
+		return "", err
+	}
+	if ref.PhotoID == "" {
+		return "", fmt.Errorf("链接 '%s' 不是章节页面", s)
+	}
+	return ref.PhotoID, nil
+}
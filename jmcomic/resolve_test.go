@@ -0,0 +1,150 @@
+package jmcomic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
+
+// fakeAPI 测试用的API服务，只实现用到的方法，其余方法调用时 panic
+type fakeAPI struct {
+	API
+	details     map[string]*ComicDetail
+	photos      map[string]*PhotoInfo
+	detailErr   error // 不为 nil 时 Detail 总是返回该错误
+	detailCalls int
+	photoCalls  int
+}
+
+func (f *fakeAPI) Detail(ctx context.Context, albumID string) (*ComicDetail, error) {
+	f.detailCalls++
+	if f.detailErr != nil {
+		return nil, f.detailErr
+	}
+	if d, ok := f.details[albumID]; ok {
+		return d, nil
+	}
+	return nil, &sdk.APIError{Code: sdk.ErrCodeNotFound, StatusCode: 404}
+}
+
+func (f *fakeAPI) Photo(ctx context.Context, photoID string) (*PhotoInfo, error) {
+	f.photoCalls++
+	if p, ok := f.photos[photoID]; ok {
+		return p, nil
+	}
+	return nil, &sdk.APIError{Code: sdk.ErrCodeNotFound, StatusCode: 404}
+}
+
+// withTestAPI 在测试中使用 f 作为命令处理器的API，测试结束后恢复
+func withTestAPI(t *testing.T, f *fakeAPI) {
+	t.Helper()
+	saved := api
+	api = f
+	t.Cleanup(func() { api = saved })
+}
+
+func TestParseIDRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		album   sdk.AlbumID
+		photo   sdk.PhotoID
+		bare    bool
+		wantErr bool
+	}{
+		{in: "350234", album: "350234", bare: true},
+		{in: "jm350234", album: "350234"},
+		{in: "JM 350234", album: "350234"},
+		{in: "<jm350234>，", album: "350234"},
+		{in: "https://18comic.vip/album/350234/some-title", album: "350234"},
+		{in: "18comic.vip/album/350234", album: "350234"},
+		{in: "https://m.jmcomic.me/photo/422866", photo: "422866"},
+		{in: "https://18comic-mirror.xyz/Photo/422866/", photo: "422866"},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "https://example.com/album/350234", wantErr: true},
+		{in: "https://18comic.vip/search?q=1", wantErr: true},
+		{in: "https://18comic.vip/album/abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		ref, err := parseIDRef(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseIDRef(%q) = %+v，期望失败", tt.in, ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseIDRef(%q) 失败: %v", tt.in, err)
+			continue
+		}
+		if ref.AlbumID != tt.album || ref.PhotoID != tt.photo || ref.bare != tt.bare {
+			t.Errorf("parseIDRef(%q) = %+v，期望漫画 %q 章节 %q bare=%v", tt.in, ref, tt.album, tt.photo, tt.bare)
+		}
+	}
+}
+
+func TestResolveIDRef(t *testing.T) {
+	newAPI := func() *fakeAPI {
+		return &fakeAPI{
+			details: map[string]*ComicDetail{"350234": {ID: "350234"}},
+			photos: map[string]*PhotoInfo{
+				"422866": {ID: "422866", AlbumID: "350234"},
+				"500000": {ID: "500000", AlbumID: "bad"},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		in          string
+		album       sdk.AlbumID
+		photo       sdk.PhotoID
+		wantErr     bool
+		detailCalls int
+		photoCalls  int
+	}{
+		{name: "纯数字的漫画ID", in: "350234", album: "350234", detailCalls: 1},
+		{name: "纯数字找不到漫画时作为章节ID", in: "422866", album: "350234", photo: "422866", detailCalls: 1, photoCalls: 1},
+		{name: "漫画和章节都不存在", in: "999999", wantErr: true, detailCalls: 1, photoCalls: 1},
+		{name: "带前缀的ID不尝试章节", in: "jm422866", album: "422866"},
+		{name: "漫画链接", in: "https://18comic.vip/album/350234", album: "350234"},
+		{name: "章节链接", in: "https://18comic.vip/photo/422866", album: "350234", photo: "422866", photoCalls: 1},
+		{name: "章节不存在", in: "https://18comic.vip/photo/999999", wantErr: true, photoCalls: 1},
+		{name: "API返回的漫画ID无效", in: "https://18comic.vip/photo/500000", wantErr: true, photoCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAPI()
+			withTestAPI(t, f)
+			ref, err := parseIDRef(tt.in)
+			if err != nil {
+				t.Fatalf("parseIDRef(%q) 失败: %v", tt.in, err)
+			}
+			ref, err = resolveIDRef(context.Background(), ref)
+			switch {
+			case tt.wantErr && err == nil:
+				t.Fatalf("resolveIDRef(%q) = %+v，期望失败", tt.in, ref)
+			case !tt.wantErr && err != nil:
+				t.Fatalf("resolveIDRef(%q) 失败: %v", tt.in, err)
+			case !tt.wantErr && (ref.AlbumID != tt.album || ref.PhotoID != tt.photo):
+				t.Fatalf("resolveIDRef(%q) = %+v，期望漫画 %q 章节 %q", tt.in, ref, tt.album, tt.photo)
+			}
+			if f.detailCalls != tt.detailCalls || f.photoCalls != tt.photoCalls {
+				t.Fatalf("请求了 %d 次详情、%d 次章节，期望 %d 和 %d", f.detailCalls, f.photoCalls, tt.detailCalls, tt.photoCalls)
+			}
+		})
+	}
+}
+
+func TestResolveIDRefKeepsOtherErrors(t *testing.T) {
+	// 查询详情出现其他错误时不尝试章节，交给随后的请求处理
+	f := &fakeAPI{detailErr: &sdk.APIError{Code: sdk.ErrCodeTimeout}}
+	withTestAPI(t, f)
+	ref, err := resolveIDRef(context.Background(), idRef{AlbumID: "350234", bare: true})
+	if err != nil || ref.AlbumID != "350234" || ref.PhotoID != "" {
+		t.Fatalf("resolveIDRef = %+v, %v，期望原样返回", ref, err)
+	}
+	if f.photoCalls != 0 {
+		t.Fatal("超时时不应尝试作为章节ID")
+	}
+}
@@ -0,0 +1,265 @@
+package jmcomic
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 各类字段清理后保留的最大字符数 (按rune计)，只用于防止异常数据，展示时另有更短的限制
+const (
+	maxShortRunes       = 64   // ID、序号、错误码等
+	maxNameRunes        = 100  // 作者、来源站点
+	maxTitleRunes       = 200  // 标题、任务信息、文件名
+	maxTagsRunes        = 300  // 标签
+	maxURLRunes         = 2048 // 链接、路径提示
+	maxDescriptionRunes = 2000 // 简介
+)
+
+// cqCodeRegex CQ码的开头，例如 "[CQ:at,qq=all]" 中的 "[CQ:"
+var cqCodeRegex = regexp.MustCompile(`(?i)\[(CQ:)`)
+
+// sanitizeText 清理直接拼进消息 (不经过模板) 的单行文本: 清理方式同 cleanField，并转义CQ码
+// maxRunes <= 0 表示不截断
+func sanitizeText(s string, maxRunes int) string {
+	return escapeCQ(cleanField(s, maxRunes))
+}
+
+// sanitizeBlock 与 sanitizeText 相同，但保留换行
+func sanitizeBlock(s string, maxRunes int) string {
+	return escapeCQ(cleanBlock(s, maxRunes))
+}
+
+// cleanField 清理来自API服务的单行文本: 去掉控制字符、双向文本控制符和零宽字符，
+// 空白合并为一个空格，按字符截断；不转义CQ码，文件名、ComicInfo.xml 等保持原样，发送消息时再转义
+func cleanField(s string, maxRunes int) string {
+	return truncateRunes(cleanText(s, false), maxRunes)
+}
+
+// cleanBlock 与 cleanField 相同，但保留换行: 连续多个换行最多保留一个空行
+func cleanBlock(s string, maxRunes int) string {
+	return truncateRunes(cleanText(s, true), maxRunes)
+}
+
+// cleanText 去掉不可见和格式控制字符并合并空白，无效的UTF-8字节也会被去掉
+func cleanText(s string, keepNewlines bool) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	pendingSpace, pendingNewlines := false, 0
+	for _, r := range s {
+		switch {
+		case r == '\n' && keepNewlines:
+			pendingNewlines++
+			continue
+		case unicode.IsSpace(r):
+			pendingSpace = true
+			continue
+		case r == utf8.RuneError, unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			// Cf 包含零宽字符 (U+200B 等)、双向文本控制符 (U+202A-U+202E、U+2066-U+2069) 和 BOM
+			continue
+		}
+		if b.Len() > 0 {
+			switch {
+			case pendingNewlines >= 2:
+				b.WriteString("\n\n")
+			case pendingNewlines == 1:
+				b.WriteByte('\n')
+			case pendingSpace:
+				b.WriteByte(' ')
+			}
+		}
+		pendingSpace, pendingNewlines = false, 0
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// truncateRunes 按字符截断，超出时以 "…" 结尾，不会截断多字节字符
+func truncateRunes(s string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	return string([]rune(s)[:maxRunes]) + "…"
+}
+
+// escapeCQ 转义CQ码的开头，使其在按CQ码解析消息的适配器中显示为普通文本
+// 只处理 "[CQ:"，标题中常见的 "[中文]" 这样的方括号保持原样；对已转义的文本再次调用不会改变结果
+func escapeCQ(s string) string {
+	return cqCodeRegex.ReplaceAllString(s, "&#91;$1")
+}
+
+// sanitizeSearchItem 返回清理后的搜索结果副本
+func sanitizeSearchItem(item ComicSearchResultItem) ComicSearchResultItem {
+	return ComicSearchResultItem{
+		ID:          cleanField(item.ID, maxShortRunes),
+		Title:       cleanField(item.Title, maxTitleRunes),
+		Author:      cleanField(item.Author, maxNameRunes),
+		Tags:        cleanField(item.Tags, maxTagsRunes),
+		Description: cleanBlock(item.Description, maxDescriptionRunes),
+		CoverURL:    cleanField(item.CoverURL, maxURLRunes),
+		SourceSite:  cleanField(item.SourceSite, maxNameRunes),
+	}
+}
+
+// sanitizeSearchPage 返回清理后的搜索结果页副本
+// Options 保持原样，翻页时需要用原始关键词再次搜索，展示关键词时由调用方清理
+func sanitizeSearchPage(page SearchPage) SearchPage {
+	items := make([]ComicSearchResultItem, len(page.Items))
+	for i, item := range page.Items {
+		items[i] = sanitizeSearchItem(item)
+	}
+	page.Items = items
+	return page
+}
+
+// sanitizeChapter 返回清理后的章节信息副本
+func sanitizeChapter(chapter ChapterInfo) ChapterInfo {
+	return ChapterInfo{
+		ID:        cleanField(chapter.ID, maxShortRunes),
+		Title:     cleanField(chapter.Title, maxTitleRunes),
+		Index:     cleanField(chapter.Index, maxShortRunes),
+		PageCount: chapter.PageCount,
+	}
+}
+
+// sanitizeDetail 返回清理后的漫画详情副本
+func sanitizeDetail(detail ComicDetail) ComicDetail {
+	chapters := make([]ChapterInfo, len(detail.Chapters))
+	for i, chapter := range detail.Chapters {
+		chapters[i] = sanitizeChapter(chapter)
+	}
+	return ComicDetail{
+		ID:          cleanField(detail.ID, maxShortRunes),
+		Title:       cleanField(detail.Title, maxTitleRunes),
+		Author:      cleanField(detail.Author, maxNameRunes),
+		Tags:        cleanField(detail.Tags, maxTagsRunes),
+		Description: cleanBlock(detail.Description, maxDescriptionRunes),
+		CoverURL:    cleanField(detail.CoverURL, maxURLRunes),
+		Chapters:    chapters,
+		SourceSite:  cleanField(detail.SourceSite, maxNameRunes),
+	}
+}
+
+// sanitizeJobFile 返回清理后的任务文件信息副本
+func sanitizeJobFile(f JobFile) JobFile {
+	return JobFile{
+		Index:        f.Index,
+		ChapterID:    cleanField(f.ChapterID, maxShortRunes),
+		ChapterIndex: cleanField(f.ChapterIndex, maxShortRunes),
+		Name:         cleanField(f.Name, maxTitleRunes),
+		Size:         f.Size,
+	}
+}
+
+// sanitizeJobStatus 返回清理后的任务状态副本
+func sanitizeJobStatus(status JobStatus) JobStatus {
+	chapterIDs := make([]string, len(status.ChapterIDs))
+	for i, id := range status.ChapterIDs {
+		chapterIDs[i] = cleanField(id, maxShortRunes)
+	}
+	var files []JobFile
+	if status.Files != nil {
+		files = make([]JobFile, len(status.Files))
+		for i, f := range status.Files {
+			files[i] = sanitizeJobFile(f)
+		}
+	}
+	status.JobID = cleanField(status.JobID, maxShortRunes)
+	status.AlbumID = cleanField(status.AlbumID, maxShortRunes)
+	status.ChapterIDs = chapterIDs
+	status.State = JobState(cleanField(string(status.State), maxShortRunes))
+	status.Message = cleanField(status.Message, maxTitleRunes)
+	status.Error = cleanField(status.Error, maxDescriptionRunes)
+	status.ErrorCode = cleanField(status.ErrorCode, maxShortRunes)
+	status.DownloadPathHint = cleanField(status.DownloadPathHint, maxURLRunes)
+	status.Files = files
+	return status
+}
+
+// sanitizedAPI 清理API返回的全部文本字段 (见 cleanField)，命令处理器只使用清理后的副本
+// 放在缓存外层，缓存中保存的是原始数据，每次返回新的副本；CQ码在发送前由 renderText、renderChain 转义
+type sanitizedAPI struct {
+	API
+}
+
+// Search 搜索漫画
+func (s sanitizedAPI) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	page, err := s.API.Search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	clean := sanitizeSearchPage(*page)
+	return &clean, nil
+}
+
+// Detail 获取漫画详情
+func (s sanitizedAPI) Detail(ctx context.Context, albumID string) (*ComicDetail, error) {
+	detail, err := s.API.Detail(ctx, albumID)
+	if err != nil {
+		return nil, err
+	}
+	clean := sanitizeDetail(*detail)
+	return &clean, nil
+}
+
+// Photo 获取章节信息
+func (s sanitizedAPI) Photo(ctx context.Context, photoID string) (*PhotoInfo, error) {
+	photo, err := s.API.Photo(ctx, photoID)
+	if err != nil {
+		return nil, err
+	}
+	return &PhotoInfo{
+		ID:      cleanField(photo.ID, maxShortRunes),
+		AlbumID: cleanField(photo.AlbumID, maxShortRunes),
+		Title:   cleanField(photo.Title, maxTitleRunes),
+		Index:   photo.Index,
+	}, nil
+}
+
+// ChapterPages 获取章节页面信息
+func (s sanitizedAPI) ChapterPages(ctx context.Context, photoID string, limit int) ([]PageImage, error) {
+	pages, err := s.API.ChapterPages(ctx, photoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	clean := make([]PageImage, len(pages))
+	for i, p := range pages {
+		clean[i] = PageImage{Index: p.Index, Filename: cleanField(p.Filename, maxTitleRunes), URL: cleanField(p.URL, maxURLRunes)}
+	}
+	return clean, nil
+}
+
+// Download 提交下载任务
+func (s sanitizedAPI) Download(ctx context.Context, albumID string, chapterIDs []string) (*JobStatus, error) {
+	status, err := s.API.Download(ctx, albumID, chapterIDs)
+	if err != nil {
+		return nil, err
+	}
+	clean := sanitizeJobStatus(*status)
+	return &clean, nil
+}
+
+// Job 查询任务状态
+func (s sanitizedAPI) Job(ctx context.Context, jobID string) (*JobStatus, error) {
+	status, err := s.API.Job(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	clean := sanitizeJobStatus(*status)
+	return &clean, nil
+}
+
+// JobFiles 列出任务文件
+func (s sanitizedAPI) JobFiles(ctx context.Context, jobID string) ([]JobFile, error) {
+	files, err := s.API.JobFiles(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	clean := make([]JobFile, len(files))
+	for i, f := range files {
+		clean[i] = sanitizeJobFile(f)
+	}
+	return clean, nil
+}
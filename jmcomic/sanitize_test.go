@@ -0,0 +1,83 @@
+package jmcomic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCleanField(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"  标题\t\t名 ", 0, "标题 名"},
+		{"a\u200bb\u202ec\x00d", 0, "abcd"},
+		{"[CQ:at,qq=all] 标题", 0, "[CQ:at,qq=all] 标题"}, // 数据层不转义
+		{"[中文] 标题", 0, "[中文] 标题"},
+		{"一二三四五", 3, "一二三…"},
+		{"abc", 3, "abc"},
+	}
+	for _, tt := range tests {
+		if got := cleanField(tt.in, tt.max); got != tt.want {
+			t.Errorf("cleanField(%q, %d) = %q，期望 %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+	if got := cleanBlock("第一行\n\n\n\n第二行\n", 0); got != "第一行\n\n第二行" {
+		t.Errorf("cleanBlock 结果为 %q", got)
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"[CQ:at,qq=all]", "&#91;CQ:at,qq=all]"},
+		{"[cq:image,file=x] [中文]", "&#91;cq:image,file=x] [中文]"},
+		{"&#91;CQ:at,qq=all]", "&#91;CQ:at,qq=all]"}, // 重复转义不改变结果
+	}
+	for _, tt := range tests {
+		if got := sanitizeText(tt.in, 0); got != tt.want {
+			t.Errorf("sanitizeText(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// rawDetailAPI 返回带有CQ码和控制字符的详情
+type rawDetailAPI struct{ API }
+
+func (rawDetailAPI) Detail(ctx context.Context, albumID string) (*ComicDetail, error) {
+	return &ComicDetail{ID: albumID, Title: "[CQ:at,qq=all]\u200b标题", Author: "作者\x07", Tags: "a,  b"}, nil
+}
+
+func TestCQEscapedAtRenderTime(t *testing.T) {
+	detail, err := sanitizedAPI{rawDetailAPI{}}.Detail(context.Background(), "350234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 数据层只清理控制字符，标题可以原样用于文件名和 ComicInfo.xml
+	if detail.Title != "[CQ:at,qq=all]标题" || detail.Author != "作者" || detail.Tags != "a, b" {
+		t.Fatalf("清理后的详情为 %+v", detail)
+	}
+
+	text := renderText(tmplRecognize, detail)
+	if strings.Contains(text, "[CQ:") || !strings.Contains(text, "&#91;CQ:at,qq=all]标题") {
+		t.Fatalf("renderText 未转义CQ码: %q", text)
+	}
+	for _, seg := range renderChain(tmplRecognize, detail, nil) {
+		if strings.Contains(seg.Data["text"], "[CQ:") {
+			t.Fatalf("renderChain 未转义CQ码: %q", seg.Data["text"])
+		}
+	}
+}
+
+func TestPreviewHeaderEscaped(t *testing.T) {
+	detail := &ComicDetail{ID: "350234", Title: "[CQ:at,qq=all]标题"}
+	chapter := ChapterInfo{ID: "422866", Index: "1", Title: "[CQ:image,file=http://x]", PageCount: 20}
+	text := previewHeader(detail, chapter)
+	if strings.Contains(text, "[CQ:") {
+		t.Fatalf("转发节点内容未转义CQ码: %q", text)
+	}
+	if want := "&#91;CQ:at,qq=all]标题\n第 1 章: &#91;CQ:image,file=http://x] (共 20 页)"; text != want {
+		t.Fatalf("previewHeader = %q，期望 %q", text, want)
+	}
+}
@@ -0,0 +1,305 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyWeight 延迟滑动平均中新样本所占的比例
+const latencyWeight = 0.3
+
+// backend 一个API后端及其运行状态
+type backend struct {
+	url     string
+	weight  int
+	breaker *circuitBreaker
+	current int // 平滑加权轮询的当前权重，由 backendPool.mu 保护
+	log     Logger
+
+	mu        sync.Mutex
+	drained   bool          // 被管理员停用，不再接收新请求，已提交任务的查询仍发往该后端
+	healthy   bool          // 最近一次健康检查的结果
+	latency   time.Duration // 请求耗时的滑动平均，0 表示尚无数据
+	lastError string
+	requests  int64
+	failures  int64
+}
+
+// observe 记录一次请求的结果，failed 表示后端故障 (网络错误或5xx)
+func (b *backend) observe(latency time.Duration, failed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests++
+	if failed {
+		b.failures++
+		if err != nil {
+			b.lastError = err.Error()
+		}
+		return
+	}
+	b.addLatencyLocked(latency)
+}
+
+// addLatencyLocked 将一次耗时计入滑动平均
+func (b *backend) addLatencyLocked(latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	if b.latency == 0 {
+		b.latency = latency
+		return
+	}
+	b.latency = time.Duration(float64(b.latency)*(1-latencyWeight) + float64(latency)*latencyWeight)
+}
+
+// usable 判断后端是否可以接收新请求
+func (b *backend) usable() bool {
+	b.mu.Lock()
+	ok := !b.drained && b.healthy
+	b.mu.Unlock()
+	return ok && !b.breaker.isOpen()
+}
+
+func (b *backend) isDrained() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.drained
+}
+
+// backendPool 按配置的策略在多个API后端之间分配请求
+type backendPool struct {
+	strategy string
+	probe    func(ctx context.Context, baseURL string) (time.Duration, bool)
+	log      Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu       sync.Mutex
+	backends []*backend
+}
+
+// newBackendPool 按配置创建后端列表并启动定期健康检查，conf 须已经过 normalize
+func newBackendPool(conf *Config, probe func(ctx context.Context, baseURL string) (time.Duration, bool)) *backendPool {
+	p := &backendPool{strategy: conf.BackendStrategy, probe: probe, log: conf.Logger, stop: make(chan struct{})}
+	for _, c := range conf.backendList() {
+		u := strings.TrimRight(strings.TrimSpace(c.URL), "/")
+		if u == "" {
+			continue
+		}
+		weight := c.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		breaker := &circuitBreaker{baseURL: u, threshold: conf.BreakerFailureThreshold, cooldown: conf.BreakerCooldown, probe: probe, log: conf.Logger}
+		// 在第一次健康检查之前假定后端可用
+		p.backends = append(p.backends, &backend{url: u, weight: weight, healthy: true, breaker: breaker, log: conf.Logger})
+	}
+	p.log.Infof("[%s Backend] 已加载 %d 个API后端，选择策略: %s", logName, len(p.backends), p.strategy)
+
+	go func() {
+		p.checkAll()
+		ticker := time.NewTicker(conf.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkAll()
+			}
+		}
+	}()
+	return p
+}
+
+// close 停止健康检查
+func (p *backendPool) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// list 返回当前的后端列表
+func (p *backendPool) list() []*backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*backend(nil), p.backends...)
+}
+
+// checkAll 并发请求每个后端的 /health 并更新健康状态，停用的后端也会检查以便查看状态
+func (p *backendPool) checkAll() {
+	var wg sync.WaitGroup
+	for _, b := range p.list() {
+		wg.Add(1)
+		go func(b *backend) {
+			defer wg.Done()
+			latency, ok := p.probe(context.Background(), b.url)
+			b.mu.Lock()
+			if ok != b.healthy {
+				if ok {
+					p.log.Infof("[%s Backend] %s 已恢复", logName, b.url)
+				} else {
+					p.log.Warnf("[%s Backend] %s 健康检查失败，暂停分配请求", logName, b.url)
+				}
+			}
+			b.healthy = ok
+			if ok {
+				b.addLatencyLocked(latency)
+			} else {
+				b.lastError = "健康检查失败"
+			}
+			b.mu.Unlock()
+		}(b)
+	}
+	wg.Wait()
+}
+
+// backendCtxKey 用于在 context 中指定请求必须发往的后端
+type backendCtxKey struct{}
+
+// WithBackend 返回将请求固定到指定后端的 context，url 为空时原样返回
+// 下载任务只存在于提交它的后端上 (见 JobStatus.Backend)，查询任务状态和文件时需要固定后端
+func WithBackend(ctx context.Context, url string) context.Context {
+	if url == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, backendCtxKey{}, url)
+}
+
+// candidates 返回本次请求依次尝试的后端
+// 健康的后端按策略排序；全部不健康时退而尝试所有未停用的后端，由熔断器决定是否放行
+func (p *backendPool) candidates(ctx context.Context) ([]*backend, error) {
+	all := p.list()
+	if len(all) == 0 {
+		return nil, &APIError{Code: ErrCodeBackendUnavailable, Message: "API基础URL未在配置中设置"}
+	}
+	if pinned, _ := ctx.Value(backendCtxKey{}).(string); pinned != "" {
+		for _, b := range all {
+			if b.url == pinned {
+				return []*backend{b}, nil
+			}
+		}
+		return nil, &APIError{Code: ErrCodeBackendUnavailable, Message: fmt.Sprintf("任务所在的API后端 %s 已不在配置中", pinned)}
+	}
+
+	var usable, fallback []*backend
+	for _, b := range all {
+		if b.isDrained() {
+			continue
+		}
+		fallback = append(fallback, b)
+		if b.usable() {
+			usable = append(usable, b)
+		}
+	}
+	if len(fallback) == 0 {
+		return nil, &APIError{Code: ErrCodeBackendUnavailable, Message: "所有API后端都已停用"}
+	}
+	if len(usable) == 0 {
+		return fallback, nil
+	}
+
+	if p.strategy == StrategyLeastLatency {
+		sort.SliceStable(usable, func(i, j int) bool {
+			// 尚无延迟数据的后端排在前面，以便尽快测得延迟
+			return usable[i].avgLatency() < usable[j].avgLatency()
+		})
+		return usable, nil
+	}
+	first := p.nextWeighted(usable)
+	ordered := make([]*backend, 0, len(usable))
+	ordered = append(ordered, first)
+	for _, b := range usable {
+		if b != first {
+			ordered = append(ordered, b)
+		}
+	}
+	return ordered, nil
+}
+
+// nextWeighted 平滑加权轮询: 每次为所有后端加上各自的权重，选出当前权重最大的一个并减去总权重
+func (p *backendPool) nextWeighted(backends []*backend) *backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *backend
+	total := 0
+	for _, b := range backends {
+		b.current += b.weight
+		total += b.weight
+		if best == nil || b.current > best.current {
+			best = b
+		}
+	}
+	best.current -= total
+	return best
+}
+
+func (b *backend) avgLatency() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.latency
+}
+
+// find 按序号 (从1开始) 或URL查找后端
+func (p *backendPool) find(token string) (*backend, bool) {
+	all := p.list()
+	if n, err := strconv.Atoi(token); err == nil {
+		if n >= 1 && n <= len(all) {
+			return all[n-1], true
+		}
+		return nil, false
+	}
+	token = strings.TrimRight(token, "/")
+	for _, b := range all {
+		if b.url == token {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// setDrained 停用或恢复后端
+func (b *backend) setDrained(drained bool) {
+	b.mu.Lock()
+	b.drained = drained
+	b.mu.Unlock()
+	if drained {
+		b.log.Infof("[%s Backend] %s 已停用", logName, b.url)
+	} else {
+		b.log.Infof("[%s Backend] %s 已恢复使用", logName, b.url)
+	}
+}
+
+// BackendStatus 一个API后端的运行状态
+type BackendStatus struct {
+	URL         string
+	Weight      int
+	Drained     bool          // 是否已被停用
+	Healthy     bool          // 最近一次健康检查是否通过
+	BreakerOpen bool          // 熔断器是否打开
+	Latency     time.Duration // 请求耗时的滑动平均，0 表示尚无数据
+	Requests    int64
+	Failures    int64
+	LastError   string
+}
+
+// status 返回后端当前状态的快照
+func (b *backend) status() BackendStatus {
+	open := b.breaker.isOpen()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BackendStatus{
+		URL:         b.url,
+		Weight:      b.weight,
+		Drained:     b.drained,
+		Healthy:     b.healthy,
+		BreakerOpen: open,
+		Latency:     b.latency,
+		Requests:    b.requests,
+		Failures:    b.failures,
+		LastError:   b.lastError,
+	}
+}
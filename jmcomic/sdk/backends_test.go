@@ -0,0 +1,129 @@
+package sdk
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestPool 创建不启动健康检查的后端列表，后端地址依次为 a、b、c...
+func newTestPool(strategy string, weights ...int) *backendPool {
+	p := &backendPool{strategy: strategy, log: nopLogger{}}
+	for i, w := range weights {
+		u := string(rune('a' + i))
+		p.backends = append(p.backends, &backend{url: u, weight: w, healthy: true, breaker: &circuitBreaker{baseURL: u, log: nopLogger{}}, log: nopLogger{}})
+	}
+	return p
+}
+
+func candidateURLs(t *testing.T, p *backendPool, ctx context.Context) []string {
+	t.Helper()
+	list, err := p.candidates(ctx)
+	if err != nil {
+		t.Fatalf("candidates 失败: %v", err)
+	}
+	urls := make([]string, 0, len(list))
+	for _, b := range list {
+		urls = append(urls, b.url)
+	}
+	return urls
+}
+
+func TestNextWeighted(t *testing.T) {
+	p := newTestPool(StrategyRoundRobin, 5, 1, 1)
+	var got []string
+	for i := 0; i < 14; i++ {
+		got = append(got, p.nextWeighted(p.backends).url)
+	}
+	// 平滑加权轮询不会连续集中选择权重大的后端
+	want := []string{"a", "a", "b", "a", "c", "a", "a", "a", "a", "b", "a", "c", "a", "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("选择顺序为 %v，期望 %v", got, want)
+	}
+}
+
+func TestNextWeightedEqual(t *testing.T) {
+	p := newTestPool(StrategyRoundRobin, 1, 1, 1)
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, p.nextWeighted(p.backends).url)
+	}
+	if want := []string{"a", "b", "c", "a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("选择顺序为 %v，期望 %v", got, want)
+	}
+}
+
+func TestCandidatesRoundRobin(t *testing.T) {
+	p := newTestPool(StrategyRoundRobin, 1, 1, 1)
+	ctx := context.Background()
+	tests := [][]string{
+		{"a", "b", "c"},
+		{"b", "a", "c"},
+		{"c", "a", "b"},
+	}
+	for i, want := range tests {
+		if got := candidateURLs(t, p, ctx); !reflect.DeepEqual(got, want) {
+			t.Fatalf("第 %d 次请求的后端顺序为 %v，期望 %v", i+1, got, want)
+		}
+	}
+}
+
+func TestCandidatesLeastLatency(t *testing.T) {
+	p := newTestPool(StrategyLeastLatency, 1, 1, 1, 1)
+	p.backends[0].latency = 30 * time.Millisecond
+	p.backends[1].latency = 10 * time.Millisecond
+	p.backends[3].latency = 10 * time.Millisecond
+	// 没有延迟数据的 c 排在最前，延迟相同时保持配置顺序
+	if got, want := candidateURLs(t, p, context.Background()), []string{"c", "b", "d", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("后端顺序为 %v，期望 %v", got, want)
+	}
+}
+
+func TestCandidatesSkipsUnusable(t *testing.T) {
+	p := newTestPool(StrategyRoundRobin, 1, 1, 1, 1)
+	p.backends[0].drained = true
+	p.backends[1].healthy = false
+	p.backends[3].breaker = &circuitBreaker{baseURL: "d", threshold: 1, cooldown: time.Hour, log: nopLogger{}}
+	p.backends[3].breaker.record(true)
+	if got, want := candidateURLs(t, p, context.Background()), []string{"c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("后端为 %v，期望只有可用的 %v", got, want)
+	}
+}
+
+func TestCandidatesAllUnhealthy(t *testing.T) {
+	p := newTestPool(StrategyLeastLatency, 1, 1, 1)
+	p.backends[0].healthy = false
+	p.backends[1].drained = true
+	p.backends[2].healthy = false
+	// 全部不可用时按配置顺序尝试所有未停用的后端
+	if got, want := candidateURLs(t, p, context.Background()), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("后端为 %v，期望 %v", got, want)
+	}
+}
+
+func TestCandidatesErrors(t *testing.T) {
+	empty := newTestPool(StrategyRoundRobin)
+	if _, err := empty.candidates(context.Background()); CodeOf(err) != ErrCodeBackendUnavailable {
+		t.Fatalf("没有后端时返回 %v", err)
+	}
+
+	p := newTestPool(StrategyRoundRobin, 1, 1)
+	p.backends[0].drained = true
+	p.backends[1].drained = true
+	if _, err := p.candidates(context.Background()); CodeOf(err) != ErrCodeBackendUnavailable {
+		t.Fatalf("全部停用时返回 %v", err)
+	}
+}
+
+func TestCandidatesPinned(t *testing.T) {
+	p := newTestPool(StrategyRoundRobin, 1, 1)
+	p.backends[1].drained = true
+	// 固定的后端即使已停用也会返回，以便查询已提交的任务
+	if got, want := candidateURLs(t, p, WithBackend(context.Background(), "b")), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("后端为 %v，期望 %v", got, want)
+	}
+	if _, err := p.candidates(WithBackend(context.Background(), "x")); CodeOf(err) != ErrCodeBackendUnavailable {
+		t.Fatalf("固定的后端不在配置中时返回 %v", err)
+	}
+}
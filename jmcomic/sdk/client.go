@@ -0,0 +1,122 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Searcher 搜索漫画
+type Searcher interface {
+	Search(ctx context.Context, opts SearchOptions) (*SearchPage, error)
+}
+
+// DetailFetcher 获取漫画详情和章节所属的漫画
+type DetailFetcher interface {
+	Detail(ctx context.Context, albumID string) (*ComicDetail, error)
+	Photo(ctx context.Context, photoID string) (*PhotoInfo, error)
+}
+
+// JobGetter 查询下载任务状态
+type JobGetter interface {
+	Job(ctx context.Context, jobID string) (*JobStatus, error)
+}
+
+// Downloader 提交下载任务并查询任务状态和文件
+type Downloader interface {
+	JobGetter
+	Download(ctx context.Context, albumID string, chapterIDs []string) (*JobStatus, error) // chapterIDs 为空时下载全部章节
+	JobFiles(ctx context.Context, jobID string) ([]JobFile, error)
+	FetchJobFile(ctx context.Context, jobID string, fileIndex int, dst io.Writer, maxBytes int64) (int64, error)
+}
+
+// ImageFetcher 获取章节页面和封面图片
+type ImageFetcher interface {
+	ChapterPages(ctx context.Context, photoID string, limit int) ([]PageImage, error)
+	PageImage(ctx context.Context, photoID string, pageIndex int, maxBytes int64) ([]byte, error)
+	Cover(ctx context.Context, albumID string, dst io.Writer, maxBytes int64) (int64, error)
+}
+
+// API 以上全部接口，*Client 是基于Python API服务的实现
+type API interface {
+	Searcher
+	DetailFetcher
+	Downloader
+	ImageFetcher
+}
+
+// Client 访问Python API服务的客户端，并发安全
+// 请求的超时只由传入的 context 决定；接受ID的方法会先用 ParseAlbumID/ParsePhotoID 校验，
+// 格式不正确时不发起请求，直接返回 *IDError
+type Client struct {
+	http        *http.Client
+	backends    *backendPool
+	clientTypes *clientSelector
+	log         Logger
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+var _ API = (*Client)(nil)
+
+// NewClient 按配置创建客户端，并开始定期检查各API后端的健康状态
+// 不再使用时应调用 Close 停止健康检查
+func NewClient(conf Config) *Client {
+	conf.normalize()
+	c := &Client{
+		http:           &http.Client{},
+		clientTypes:    newClientSelector(&conf),
+		log:            conf.Logger,
+		maxRetries:     conf.MaxRetries,
+		retryBaseDelay: conf.RetryBaseDelay,
+		retryMaxDelay:  conf.RetryMaxDelay,
+	}
+	c.backends = newBackendPool(&conf, c.probeHealth)
+	return c
+}
+
+// Close 停止后台的健康检查
+func (c *Client) Close() {
+	c.backends.close()
+}
+
+// Backends 返回各API后端的当前状态，顺序与配置一致
+func (c *Client) Backends() []BackendStatus {
+	list := c.backends.list()
+	statuses := make([]BackendStatus, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, b.status())
+	}
+	return statuses
+}
+
+// SetBackendDrained 停用或恢复一个API后端，target 为序号 (从1开始) 或URL
+// 停用的后端不再接收新请求，已提交到该后端的任务仍可通过 WithBackend 查询
+func (c *Client) SetBackendDrained(target string, drained bool) (url string, ok bool) {
+	b, ok := c.backends.find(target)
+	if !ok {
+		return "", false
+	}
+	b.setDrained(drained)
+	return b.url, true
+}
+
+// ClientType 返回客户端类型的选择状态
+func (c *Client) ClientType() ClientTypeStatus {
+	return c.clientTypes.status()
+}
+
+// SetClientType 设置客户端类型: ClientHTML、ClientAPI 固定使用一种，ClientAuto 自动切换
+func (c *Client) SetClientType(mode string) error {
+	switch mode {
+	case ClientHTML, ClientAPI, ClientAuto:
+	default:
+		return fmt.Errorf("未知的客户端类型 '%s'", mode)
+	}
+	c.clientTypes.setMode(mode)
+	return nil
+}
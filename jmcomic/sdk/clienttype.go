@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"sync"
+	"time"
+)
+
+// clientSelector 记录当前使用的客户端类型
+// 自动模式下，配置的类型失败而另一种成功后，在 decay 时间内优先使用另一种，
+// 到期后重新尝试配置的类型
+type clientSelector struct {
+	preferred string        // 配置的客户端类型
+	decay     time.Duration // 改用另一种类型后的有效期
+	log       Logger
+
+	mu      sync.Mutex
+	mode    string    // ClientAuto 或固定的客户端类型
+	active  string    // 自动模式下暂时改用的类型，为空时使用配置的类型
+	expires time.Time // active 的有效期
+}
+
+// newClientSelector 按配置创建客户端类型选择器，关闭自动切换时固定使用配置的类型
+func newClientSelector(conf *Config) *clientSelector {
+	s := &clientSelector{preferred: conf.ClientType, decay: conf.ClientFallbackDecay, log: conf.Logger, mode: ClientAuto}
+	if conf.DisableClientFallback {
+		s.mode = conf.ClientType
+	}
+	return s
+}
+
+// alternateClient 返回另一种客户端类型
+func alternateClient(clientType string) string {
+	if clientType == ClientAPI {
+		return ClientHTML
+	}
+	return ClientAPI
+}
+
+// current 返回本次请求应使用的客户端类型
+func (s *clientSelector) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mode != ClientAuto {
+		return s.mode
+	}
+	if s.active != "" && time.Now().Before(s.expires) {
+		return s.active
+	}
+	s.active = ""
+	return s.preferred
+}
+
+// canFallback 判断失败时是否可以改用另一种客户端重试
+func (s *clientSelector) canFallback() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mode == ClientAuto
+}
+
+// recordFallback 在 failed 类型失败、ok 类型成功后调用，记住当前可用的类型
+func (s *clientSelector) recordFallback(failed, ok string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok == s.preferred {
+		s.active = ""
+	} else {
+		s.active = ok
+		s.expires = time.Now().Add(s.decay)
+	}
+	s.log.Warnf("[%s Client] %s 客户端请求失败，已改用 %s 客户端", logName, failed, ok)
+}
+
+// setMode 设置客户端类型模式，切换时清除自动模式的记忆
+func (s *clientSelector) setMode(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+	s.active = ""
+}
+
+// ClientTypeStatus 客户端类型的选择状态
+type ClientTypeStatus struct {
+	Mode      string    // ClientAuto 或固定的客户端类型
+	Preferred string    // 配置的客户端类型
+	Current   string    // 当前请求使用的客户端类型
+	Until     time.Time // 自动模式下改用另一种类型时，重新尝试 Preferred 的时间，否则为零值
+}
+
+// status 返回当前的选择状态
+func (s *clientSelector) status() ClientTypeStatus {
+	current := s.current()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := ClientTypeStatus{Mode: s.mode, Preferred: s.preferred, Current: current}
+	if s.mode == ClientAuto && s.active != "" {
+		st.Until = s.expires
+	}
+	return st
+}
@@ -0,0 +1,145 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clientTypeServer 模拟的API服务，按请求的 client_type 返回设定的状态码
+type clientTypeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses map[string]int // 客户端类型 -> 状态码，未设置时返回 200
+	requests []string       // 依次收到的请求使用的客户端类型
+}
+
+func newClientTypeServer(t *testing.T, statuses map[string]int) *clientTypeServer {
+	t.Helper()
+	s := &clientTypeServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			return
+		}
+		clientType := r.URL.Query().Get("client_type")
+		s.mu.Lock()
+		s.requests = append(s.requests, clientType)
+		status, ok := s.statuses[clientType]
+		s.mu.Unlock()
+		if ok && status >= 400 {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"status":"error","message":"模拟错误"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// takeRequests 返回并清空收到的请求
+func (s *clientTypeServer) takeRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func newClientTypeClient(t *testing.T, baseURL string, decay time.Duration, fallback bool) *Client {
+	t.Helper()
+	conf := DefaultConfig()
+	conf.BaseURL = baseURL
+	conf.HealthCheckInterval = time.Hour
+	conf.MaxRetries = 0
+	conf.ClientType = ClientHTML
+	conf.ClientFallbackDecay = decay
+	conf.DisableClientFallback = !fallback
+	c := NewClient(conf)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestClientTypeFallback(t *testing.T) {
+	s := newClientTypeServer(t, map[string]int{ClientHTML: http.StatusInternalServerError})
+	c := newClientTypeClient(t, s.URL, time.Hour, true)
+	search := func() error {
+		_, err := c.Search(context.Background(), SearchOptions{Keyword: "k"})
+		return err
+	}
+
+	// html 客户端失败后改用 api 客户端重试
+	if err := search(); err != nil {
+		t.Fatalf("改用另一种客户端后应当成功: %v", err)
+	}
+	if got := s.takeRequests(); !reflect.DeepEqual(got, []string{ClientHTML, ClientAPI}) {
+		t.Fatalf("请求使用的客户端类型为 %v，期望先 html 后 api", got)
+	}
+	st := c.ClientType()
+	if st.Mode != ClientAuto || st.Current != ClientAPI || st.Until.IsZero() {
+		t.Fatalf("切换后的状态为 %+v，期望自动模式下暂时使用 api", st)
+	}
+
+	// 有效期内直接使用 api 客户端
+	if err := search(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.takeRequests(); !reflect.DeepEqual(got, []string{ClientAPI}) {
+		t.Fatalf("请求使用的客户端类型为 %v，期望只用 api", got)
+	}
+}
+
+func TestClientTypeFallbackDecay(t *testing.T) {
+	s := newClientTypeServer(t, map[string]int{ClientHTML: http.StatusInternalServerError})
+	c := newClientTypeClient(t, s.URL, 20*time.Millisecond, true)
+	if _, err := c.Search(context.Background(), SearchOptions{Keyword: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	s.takeRequests()
+	time.Sleep(30 * time.Millisecond)
+	// 到期后重新尝试配置的类型
+	s.mu.Lock()
+	s.statuses = nil
+	s.mu.Unlock()
+	if _, err := c.Search(context.Background(), SearchOptions{Keyword: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.takeRequests(); !reflect.DeepEqual(got, []string{ClientHTML}) {
+		t.Fatalf("到期后请求使用的客户端类型为 %v，期望 html", got)
+	}
+}
+
+func TestClientTypeNoFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses map[string]int
+		fallback bool
+		requests []string
+		code     ErrorCode
+	}{
+		{name: "两种都失败", statuses: map[string]int{ClientHTML: 500, ClientAPI: 500}, fallback: true, requests: []string{ClientHTML, ClientAPI}, code: ErrCodeInternal},
+		{name: "4xx不切换", statuses: map[string]int{ClientHTML: 404}, fallback: true, requests: []string{ClientHTML}, code: ErrCodeNotFound},
+		{name: "关闭自动切换", statuses: map[string]int{ClientHTML: 500}, fallback: false, requests: []string{ClientHTML}, code: ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newClientTypeServer(t, tt.statuses)
+			c := newClientTypeClient(t, s.URL, time.Hour, tt.fallback)
+			_, err := c.Search(context.Background(), SearchOptions{Keyword: "k"})
+			if CodeOf(err) != tt.code {
+				t.Fatalf("Search 返回 %v，期望错误码 %s", err, tt.code)
+			}
+			if got := s.takeRequests(); !reflect.DeepEqual(got, tt.requests) {
+				t.Fatalf("请求使用的客户端类型为 %v，期望 %v", got, tt.requests)
+			}
+			if st := c.ClientType(); st.Current != ClientHTML {
+				t.Fatalf("失败后当前客户端类型为 %q，期望仍为 html", st.Current)
+			}
+		})
+	}
+}
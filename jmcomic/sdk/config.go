@@ -0,0 +1,103 @@
+package sdk
+
+import (
+	"strings"
+	"time"
+)
+
+// 后端选择策略
+const (
+	StrategyRoundRobin   = "round_robin"   // 按权重平滑轮询
+	StrategyLeastLatency = "least_latency" // 优先选择延迟最低的后端
+)
+
+// API服务中 jmcomic 库的客户端类型
+const (
+	ClientHTML = "html"
+	ClientAPI  = "api"
+	ClientAuto = "auto" // 自动选择: 默认使用配置的类型，失败时改用另一种
+)
+
+// Backend 单个API后端的配置
+type Backend struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"` // 轮询权重，默认为1
+}
+
+// Config 创建 Client 所需的配置，零值字段使用默认值或表示关闭对应功能
+type Config struct {
+	BaseURL             string        // 只有一个API后端时使用，配置了 Backends 时忽略
+	Backends            []Backend     // 多个API后端
+	BackendStrategy     string        // 后端选择策略，默认 StrategyRoundRobin
+	HealthCheckInterval time.Duration // 后端健康检查的间隔，默认30秒
+
+	ClientType            string        // 默认使用的客户端类型，ClientHTML (默认) 或 ClientAPI
+	DisableClientFallback bool          // 关闭搜索和详情失败时自动改用另一种客户端类型
+	ClientFallbackDecay   time.Duration // 改用另一种客户端后多久重新尝试 ClientType，默认10分钟
+
+	MaxRetries              int           // GET请求失败后的最大重试次数，0 表示不重试
+	RetryBaseDelay          time.Duration // 首次重试前的基础等待时间，默认500毫秒
+	RetryMaxDelay           time.Duration // 重试等待时间上限，默认5秒
+	BreakerFailureThreshold int           // 单个后端连续失败多少次后熔断，0 表示不熔断
+	BreakerCooldown         time.Duration // 熔断后多久探测一次 /health，默认30秒
+
+	Logger Logger // 运行日志的输出，默认不输出日志
+}
+
+// DefaultConfig 返回连接到本机API服务的推荐配置
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:                 "http://localhost:5000",
+		BackendStrategy:         StrategyRoundRobin,
+		HealthCheckInterval:     30 * time.Second,
+		ClientType:              ClientHTML,
+		ClientFallbackDecay:     10 * time.Minute,
+		MaxRetries:              2,
+		RetryBaseDelay:          500 * time.Millisecond,
+		RetryMaxDelay:           5 * time.Second,
+		BreakerFailureThreshold: 5,
+		BreakerCooldown:         30 * time.Second,
+	}
+}
+
+// normalize 修正无效的配置项
+func (c *Config) normalize() {
+	if c.BackendStrategy != StrategyLeastLatency {
+		c.BackendStrategy = StrategyRoundRobin
+	}
+	if c.HealthCheckInterval <= 0 {
+		c.HealthCheckInterval = 30 * time.Second
+	}
+	if c.ClientType != ClientAPI {
+		c.ClientType = ClientHTML
+	}
+	if c.ClientFallbackDecay <= 0 {
+		c.ClientFallbackDecay = 10 * time.Minute
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = 500 * time.Millisecond
+	}
+	if c.RetryMaxDelay < c.RetryBaseDelay {
+		c.RetryMaxDelay = c.RetryBaseDelay
+	}
+	if c.BreakerFailureThreshold < 0 {
+		c.BreakerFailureThreshold = 0
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = 30 * time.Second
+	}
+	if c.Logger == nil {
+		c.Logger = nopLogger{}
+	}
+}
+
+// backendList 返回配置的后端列表，未配置 Backends 时使用 BaseURL
+func (c *Config) backendList() []Backend {
+	if len(c.Backends) == 0 && strings.TrimSpace(c.BaseURL) != "" {
+		return []Backend{{URL: c.BaseURL, Weight: 1}}
+	}
+	return c.Backends
+}
@@ -0,0 +1,24 @@
+// Package sdk 是访问 JMComic Python API服务的Go客户端，供其他ZeroBot插件使用。
+//
+// sdk 的版本 (Version) 与聊天插件分开维护: 导出的类型、方法和接口在同一主版本内保持兼容，
+// 次版本只会新增功能。已有的接口 (API、Downloader 等) 不会增加方法，可以放心自己实现；
+// 新的能力以新的小接口提供，使用方通过类型断言判断实现是否支持。聊天插件本身也通过 sdk 访问API服务。
+//
+// sdk 默认不输出日志，可以通过 Config.Logger 接入自己的日志库。
+//
+//	client := sdk.NewClient(sdk.DefaultConfig())
+//	defer client.Close()
+//	page, err := client.Search(ctx, sdk.SearchOptions{Keyword: "关键词"})
+//
+// 所有请求的超时只由传入的 context 决定。
+package sdk
+
+// Version sdk 的版本号，按语义化版本维护，与插件的版本号无关:
+// 修改了 sdk 的提交同时更新它，修复问题增加修订号，新增导出的功能增加次版本号，
+// 不兼容的修改增加主版本号
+const Version = "1.0.0"
+
+const (
+	logName   = "jmcomic-sdk" // 日志前缀
+	userAgent = "ZeroBot-JMComic-SDK/" + Version
+)
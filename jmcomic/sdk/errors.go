@@ -0,0 +1,121 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrorCode API错误的分类，与API服务响应中的 code 字段一致
+type ErrorCode string
+
+const (
+	ErrCodeNotFound           ErrorCode = "not_found"           // 漫画、章节或任务不存在
+	ErrCodeRegionBlocked      ErrorCode = "region_blocked"      // 内容在API服务所在地区不可访问
+	ErrCodeTimeout            ErrorCode = "timeout"             // 请求超时
+	ErrCodeBackendUnavailable ErrorCode = "backend_unavailable" // API服务不可用
+	ErrCodeInvalidID          ErrorCode = "invalid_id"          // ID格式不正确
+	ErrCodeRateLimited        ErrorCode = "rate_limited"        // 请求过于频繁
+	ErrCodeBadRequest         ErrorCode = "bad_request"         // 其他请求参数错误
+	ErrCodeInternal           ErrorCode = "internal"            // 无法归类的错误
+)
+
+// knownCodes API服务可能返回的全部错误码
+var knownCodes = map[ErrorCode]bool{
+	ErrCodeNotFound:           true,
+	ErrCodeRegionBlocked:      true,
+	ErrCodeTimeout:            true,
+	ErrCodeBackendUnavailable: true,
+	ErrCodeInvalidID:          true,
+	ErrCodeRateLimited:        true,
+	ErrCodeBadRequest:         true,
+	ErrCodeInternal:           true,
+}
+
+// APIError 调用API服务失败时返回的错误
+// Error() 包含API服务返回的原始信息，适合写入日志，不适合直接展示给用户
+type APIError struct {
+	Code       ErrorCode
+	StatusCode int    // HTTP状态码，未收到响应时为0
+	Message    string // API服务返回的原始信息
+	Err        error  // 底层错误，例如网络错误
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("API错误 [%s] (HTTP %d): %s", e.Code, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("API错误 [%s]: %s", e.Code, msg)
+}
+
+func (e *APIError) Unwrap() error { return e.Err }
+
+// errorCodeFromStatus 在API服务未返回 code 时，根据HTTP状态码推断错误码
+func errorCodeFromStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrCodeNotFound
+	case status == http.StatusForbidden || status == http.StatusUnavailableForLegalReasons:
+		return ErrCodeRegionBlocked
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	case status == http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable:
+		return ErrCodeBackendUnavailable
+	case status >= 400 && status < 500:
+		return ErrCodeBadRequest
+	}
+	return ErrCodeInternal
+}
+
+// newStatusError 根据HTTP错误响应创建 APIError，优先使用响应中的 code
+func newStatusError(status int, code, message string) *APIError {
+	errCode := ErrorCode(code)
+	if !knownCodes[errCode] {
+		errCode = errorCodeFromStatus(status)
+	}
+	return &APIError{Code: errCode, StatusCode: status, Message: message}
+}
+
+// newTransportError 根据网络错误创建 APIError，超时和连接失败分别归类
+func newTransportError(ctx context.Context, err error) *APIError {
+	code := ErrCodeBackendUnavailable
+	var netErr net.Error
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		code = ErrCodeTimeout
+	}
+	return &APIError{Code: code, Err: err}
+}
+
+// IsRetryable 判断错误是否为临时错误 (超时、API服务不可用、请求过于频繁等)，稍后重试可能成功
+// 漫画或任务不存在、ID或参数错误、地区限制等错误重试也不会成功
+func IsRetryable(err error) bool {
+	switch CodeOf(err) {
+	case ErrCodeNotFound, ErrCodeRegionBlocked, ErrCodeInvalidID, ErrCodeBadRequest:
+		return false
+	}
+	return true
+}
+
+// CodeOf 返回错误对应的错误码，IDError 为 ErrCodeInvalidID，其他错误按超时或内部错误处理
+func CodeOf(err error) ErrorCode {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	var idErr *IDError
+	if errors.As(err, &idErr) {
+		return ErrCodeInvalidID
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCodeTimeout
+	}
+	return ErrCodeInternal
+}
@@ -0,0 +1,69 @@
+package sdk
+
+import (
+	"context"
+	"time"
+)
+
+// JobEvent 下载任务的一次状态变化
+type JobEvent struct {
+	Status JobStatus
+	Err    error // 查询失败时不为 nil，此时 Status 为上一次查询到的状态
+}
+
+// DefaultWatchInterval WatchJob 的 interval <= 0 时使用的轮询间隔
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchJob 每隔 interval 查询一次任务状态，状态变化或查询失败时发送事件
+// 任务结束 (JobState.IsTerminal)、查询返回无法重试的错误 (见 IsRetryable，如任务不存在) 或 ctx 结束后关闭通道；
+// 任务提交在其他后端时，ctx 应通过 WithBackend 固定后端
+func WatchJob(ctx context.Context, getter JobGetter, jobID string, interval time.Duration) <-chan JobEvent {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	events := make(chan JobEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last JobStatus
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			status, err := getter.Job(ctx, jobID)
+			var event JobEvent
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return
+				}
+				event = JobEvent{Status: last, Err: err}
+			case status.State == last.State && status.Message == last.Message:
+				continue
+			default:
+				last = *status
+				event = JobEvent{Status: last}
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			if event.Err != nil && !IsRetryable(event.Err) {
+				return
+			}
+			if event.Err == nil && last.State.IsTerminal() {
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// WatchJob 见包级函数 WatchJob
+func (c *Client) WatchJob(ctx context.Context, jobID string, interval time.Duration) <-chan JobEvent {
+	return WatchJob(ctx, c, jobID, interval)
+}
@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJobGetter 依次返回预设的查询结果，用完后重复最后一个
+type fakeJobGetter struct {
+	mu      sync.Mutex
+	results []fakeJobResult
+	calls   int
+}
+
+type fakeJobResult struct {
+	state JobState
+	err   error
+}
+
+func (g *fakeJobGetter) Job(ctx context.Context, jobID string) (*JobStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := g.results[len(g.results)-1]
+	if g.calls < len(g.results) {
+		r = g.results[g.calls]
+	}
+	g.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &JobStatus{JobID: jobID, State: r.state}, nil
+}
+
+// collectEvents 读取事件直到通道关闭，超时则测试失败
+func collectEvents(t *testing.T, events <-chan JobEvent) []JobEvent {
+	t.Helper()
+	var got []JobEvent
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		case <-timeout:
+			t.Fatal("通道没有关闭")
+		}
+	}
+}
+
+func TestWatchJob(t *testing.T) {
+	unavailable := &APIError{Code: ErrCodeBackendUnavailable}
+	getter := &fakeJobGetter{results: []fakeJobResult{
+		{state: JobStateQueued}, {state: JobStateQueued}, {err: unavailable}, {state: JobStateCompleted},
+	}}
+	got := collectEvents(t, WatchJob(context.Background(), getter, "job1", time.Millisecond))
+	if len(got) != 3 {
+		t.Fatalf("收到 %d 个事件，期望 3 个 (状态未变化时不发送): %+v", len(got), got)
+	}
+	if got[1].Err != unavailable || got[1].Status.State != JobStateQueued {
+		t.Fatalf("查询失败的事件为 %+v，期望带上次的状态和错误", got[1])
+	}
+	if got[2].Status.State != JobStateCompleted {
+		t.Fatalf("最后的事件为 %+v，期望任务完成", got[2])
+	}
+}
+
+func TestWatchJobStopsOnPermanentError(t *testing.T) {
+	getter := &fakeJobGetter{results: []fakeJobResult{{err: &APIError{Code: ErrCodeNotFound}}}}
+	got := collectEvents(t, WatchJob(context.Background(), getter, "job1", time.Millisecond))
+	if len(got) != 1 || CodeOf(got[0].Err) != ErrCodeNotFound {
+		t.Fatalf("收到的事件为 %+v，期望一个 not_found 错误后关闭通道", got)
+	}
+	if getter.calls != 1 {
+		t.Fatalf("查询了 %d 次，任务不存在后不应继续查询", getter.calls)
+	}
+}
+
+func TestWatchJobInvalidInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := WatchJob(ctx, &fakeJobGetter{results: []fakeJobResult{{state: JobStateRunning}}}, "job1", 0)
+	cancel()
+	collectEvents(t, events)
+}
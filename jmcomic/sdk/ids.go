@@ -0,0 +1,116 @@
+package sdk
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxIDDigits    = 10 // 漫画和章节ID的最大位数，目前JM的ID不超过7位
+	maxJobIDLength = 64 // 任务ID的最大长度，API服务生成的是32位十六进制字符串
+	maxInputShown  = 32 // 错误信息中最多显示的输入字符数
+)
+
+// AlbumID 经过校验的漫画 (album) ID，只能由 ParseAlbumID 得到
+// 规范形式为不带前导0的ASCII数字，例如 "350234"
+type AlbumID string
+
+// PhotoID 经过校验的章节 (photo) ID，只能由 ParsePhotoID 得到，规范形式与 AlbumID 相同
+type PhotoID string
+
+// IDError ID格式不正确，Error() 说明了原因，可以直接展示给用户
+// CodeOf 对 IDError 返回 ErrCodeInvalidID
+type IDError struct {
+	Kind   string // "漫画"、"章节" 或 "任务"
+	Input  string // 原始输入
+	Reason string
+}
+
+func (e *IDError) Error() string {
+	input := e.Input
+	if utf8.RuneCountInString(input) > maxInputShown {
+		input = string([]rune(input)[:maxInputShown]) + "..."
+	}
+	return fmt.Sprintf("无效的%sID %q: %s", e.Kind, input, e.Reason)
+}
+
+// ParseAlbumID 校验并规范化漫画ID
+// 接受 "350234"、"jm350234"、"JM-350234" 和全角数字，去掉前缀和前导0；其他输入返回 *IDError
+func ParseAlbumID(s string) (AlbumID, error) {
+	id, err := parseNumericID(s, "漫画", true)
+	return AlbumID(id), err
+}
+
+// ParsePhotoID 校验并规范化章节ID，规则与 ParseAlbumID 相同，但不接受 jm 前缀
+func ParsePhotoID(s string) (PhotoID, error) {
+	id, err := parseNumericID(s, "章节", false)
+	return PhotoID(id), err
+}
+
+func (id AlbumID) String() string { return string(id) }
+
+func (id PhotoID) String() string { return string(id) }
+
+// parseNumericID 校验纯数字ID，allowPrefix 为 true 时允许 jm 前缀
+func parseNumericID(s, kind string, allowPrefix bool) (string, error) {
+	trimmed := strings.TrimSpace(s)
+	if allowPrefix && len(trimmed) >= 2 && strings.EqualFold(trimmed[:2], "jm") {
+		trimmed = strings.TrimLeft(trimmed[2:], " -_")
+	}
+	if trimmed == "" {
+		return "", &IDError{Kind: kind, Input: s, Reason: "ID不能为空"}
+	}
+	var b strings.Builder
+	for _, r := range trimmed {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= '０' && r <= '９':
+			b.WriteRune('0' + r - '０')
+		default:
+			reason := "只能包含数字，例如 350234"
+			if allowPrefix {
+				reason += " 或 jm350234"
+			}
+			return "", &IDError{Kind: kind, Input: s, Reason: reason}
+		}
+	}
+	id := strings.TrimLeft(b.String(), "0")
+	if id == "" {
+		return "", &IDError{Kind: kind, Input: s, Reason: "ID不能为0"}
+	}
+	if len(id) > maxIDDigits {
+		return "", &IDError{Kind: kind, Input: s, Reason: fmt.Sprintf("ID不能超过 %d 位", maxIDDigits)}
+	}
+	return id, nil
+}
+
+// checkJobID 校验任务ID，只允许字母、数字、"-" 和 "_"
+func checkJobID(jobID string) error {
+	if jobID == "" || len(jobID) > maxJobIDLength {
+		return &IDError{Kind: "任务", Input: jobID, Reason: fmt.Sprintf("长度应为 1 到 %d 个字符", maxJobIDLength)}
+	}
+	for i := 0; i < len(jobID); i++ {
+		c := jobID[i]
+		if !(c >= '0' && c <= '9' || c|0x20 >= 'a' && c|0x20 <= 'z' || c == '-' || c == '_') {
+			return &IDError{Kind: "任务", Input: jobID, Reason: "只能包含字母、数字、- 和 _"}
+		}
+	}
+	return nil
+}
+
+// apiPath 拼接接口路径，每一段都经过转义，"/"、"?"、"." 和 ".." 不会改变请求的接口
+func apiPath(segments ...string) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		b.WriteByte('/')
+		if seg == "." || seg == ".." {
+			b.WriteString(strings.Repeat("%2E", len(seg)))
+			continue
+		}
+		b.WriteString(url.PathEscape(seg))
+	}
+	return b.String()
+}
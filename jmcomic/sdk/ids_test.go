@@ -0,0 +1,127 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var idSeeds = []string{
+	"350234", "jm350234", "JM 422866", "JM-0012", "００１２３", "0", "",
+	"../health12", "12/../../health", "1%2F2", "123?x=1", "jm", "１２a", "99999999999",
+	"‮123", "12\x00", "https://18comic.vip/album/1",
+}
+
+// FuzzParseAlbumID 校验通过的ID一定是规范形式，且拼接到接口路径后不会改变请求的接口
+func FuzzParseAlbumID(f *testing.F) {
+	for _, seed := range idSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := ParseAlbumID(s)
+		if err != nil {
+			var idErr *IDError
+			if !errors.As(err, &idErr) || CodeOf(err) != ErrCodeInvalidID {
+				t.Fatalf("ParseAlbumID(%q) 返回了非 IDError 的错误: %v", s, err)
+			}
+			return
+		}
+		checkCanonical(t, s, string(id))
+		if again, err := ParseAlbumID(string(id)); err != nil || again != id {
+			t.Fatalf("ParseAlbumID 不是幂等的: %q -> %q -> %q, %v", s, id, again, err)
+		}
+		if photo, err := ParsePhotoID(string(id)); err != nil || string(photo) != string(id) {
+			t.Fatalf("规范的漫画ID %q 不能作为章节ID: %q, %v", id, photo, err)
+		}
+	})
+}
+
+// FuzzParsePhotoID 章节ID不接受 jm 前缀，其余规则与漫画ID相同
+func FuzzParsePhotoID(f *testing.F) {
+	for _, seed := range idSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := ParsePhotoID(s)
+		if err != nil {
+			if CodeOf(err) != ErrCodeInvalidID {
+				t.Fatalf("ParsePhotoID(%q) 返回了非 IDError 的错误: %v", s, err)
+			}
+			return
+		}
+		if strings.ContainsAny(strings.ToLower(s), "jm") {
+			t.Fatalf("ParsePhotoID(%q) 接受了 jm 前缀: %q", s, id)
+		}
+		checkCanonical(t, s, string(id))
+	})
+}
+
+// FuzzAPIPath 任意内容的路径段都只占据接口路径中的一段
+func FuzzAPIPath(f *testing.F) {
+	for _, seed := range idSeeds {
+		f.Add(seed)
+	}
+	f.Add(".")
+	f.Add("..")
+	conf := DefaultConfig()
+	c := &Client{clientTypes: newClientSelector(&conf)}
+	f.Fuzz(func(t *testing.T, seg string) {
+		u, err := c.buildAPIURL("http://127.0.0.1:5000/api/", apiPath("comic", seg), nil)
+		if err != nil {
+			t.Fatalf("buildAPIURL(%q) 失败: %v", seg, err)
+		}
+		parsed, err := url.Parse(u.String())
+		if err != nil {
+			t.Fatalf("生成的URL %q 无法解析: %v", u.String(), err)
+		}
+		parts := strings.Split(parsed.EscapedPath(), "/")
+		if len(parts) != 4 || parts[1] != "api" || parts[2] != "comic" {
+			t.Fatalf("路径段 %q 改变了接口路径: %q", seg, parsed.EscapedPath())
+		}
+		if parts[3] == "." || parts[3] == ".." {
+			t.Fatalf("路径段 %q 生成了相对路径: %q", seg, parsed.EscapedPath())
+		}
+		if got, err := url.PathUnescape(parts[3]); err != nil || got != seg {
+			t.Fatalf("路径段 %q 还原后为 %q, %v", seg, got, err)
+		}
+		if parsed.RawQuery != "client_type=html" {
+			t.Fatalf("路径段 %q 改变了查询参数: %q", seg, parsed.RawQuery)
+		}
+	})
+}
+
+func checkCanonical(t *testing.T, input, id string) {
+	t.Helper()
+	if id == "" || len(id) > maxIDDigits || id[0] == '0' {
+		t.Fatalf("%q 解析为不规范的ID %q", input, id)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '0' || id[i] > '9' {
+			t.Fatalf("%q 解析为包含非数字字符的ID %q", input, id)
+		}
+	}
+}
+
+// TestDownloadRejectsBackendJobID API返回的任务ID不能用于拼接本地路径
+func TestDownloadRejectsBackendJobID(t *testing.T) {
+	for _, jobID := range []string{"../x", "a/b", "..", "", strings.Repeat("a", maxJobIDLength+1)} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" {
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"job_id":` + strconv.Quote(jobID) + `}}`))
+		}))
+		c := newTestClient(t, srv.URL, 0, 0, time.Second)
+		status, err := c.Download(context.Background(), "350234", nil)
+		srv.Close()
+		if err == nil {
+			t.Errorf("API返回任务ID %q 时 Download 返回 %+v，期望失败", jobID, status)
+		}
+	}
+}
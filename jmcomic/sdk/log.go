@@ -0,0 +1,18 @@
+package sdk
+
+// Logger sdk 输出运行日志的接口，logrus 的 *Logger 等常见日志库可以直接使用
+// 日志内容以 "[jmcomic-sdk 模块]" 开头
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// nopLogger 未配置 Logger 时使用，丢弃所有日志
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
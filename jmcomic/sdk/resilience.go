@@ -0,0 +1,183 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errBackendUnavailable 熔断器打开时直接返回的错误，不再请求后端
+var errBackendUnavailable error = &APIError{Code: ErrCodeBackendUnavailable, Message: "熔断器已打开"}
+
+// isDialError 判断错误是否发生在建立连接阶段，此时请求还没有发送到后端
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isRetryableStatus 判断HTTP状态码是否属于值得重试的临时错误
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retryDelay 计算第 attempt 次失败后的等待时间: 以 base 为基数指数退避加随机抖动，
+// 上限为 max；服务端通过 Retry-After 要求更长的等待时以其为准
+func retryDelay(attempt int, base, max, retryAfter time.Duration) time.Duration {
+	backoff := base << (attempt - 1)
+	if backoff <= 0 || backoff > max {
+		backoff = max
+	}
+	// 在 [backoff/2, backoff) 之间取随机值，避免多个请求同时重试
+	half := backoff / 2
+	delay := half
+	if half > 0 {
+		delay += time.Duration(rand.Int63n(int64(half)))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// sleepContext 等待指定时间，ctx 结束时提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota // 正常请求
+	breakerOpen                       // 连续失败过多，直接拒绝请求
+)
+
+// circuitBreaker 后端连续失败达到阈值后打开，在冷却期内直接返回 errBackendUnavailable；
+// 冷却期过后由一个请求探测 /health，探测成功才恢复正常
+// 每个API后端各有一个熔断器
+type circuitBreaker struct {
+	baseURL   string        // 探测 /health 使用的后端地址
+	threshold int           // 连续失败多少次后打开，<= 0 表示不熔断
+	cooldown  time.Duration // 打开后多久探测一次 /health
+	probe     func(ctx context.Context, baseURL string) (time.Duration, bool)
+	log       Logger
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int // 连续失败次数
+	openedAt time.Time
+	probing  bool // 是否有请求正在探测 /health
+}
+
+// allow 判断是否允许发出请求
+func (b *circuitBreaker) allow(ctx context.Context) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	if b.state == breakerClosed {
+		b.mu.Unlock()
+		return nil
+	}
+	if time.Since(b.openedAt) < b.cooldown || b.probing {
+		b.mu.Unlock()
+		return errBackendUnavailable
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	_, healthy := b.probe(ctx, b.baseURL)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !healthy {
+		b.openedAt = time.Now()
+		b.log.Warnf("[%s Breaker] %s 健康检查失败，熔断器保持打开 %v", logName, b.baseURL, b.cooldown)
+		return errBackendUnavailable
+	}
+	b.state = breakerClosed
+	b.failures = 0
+	b.log.Infof("[%s Breaker] %s 健康检查通过，熔断器关闭", logName, b.baseURL)
+	return nil
+}
+
+// record 记录一次请求结果，failed 表示后端故障 (网络错误或5xx)
+func (b *circuitBreaker) record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		b.log.Errorf("[%s Breaker] %s 连续失败 %d 次，熔断器打开 %v", logName, b.baseURL, b.failures, b.cooldown)
+	}
+}
+
+// isOpen 判断熔断器当前是否处于打开状态
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerOpen
+}
+
+// probeHealth 请求后端的 /health，返回耗时和是否健康
+func (c *Client) probeHealth(ctx context.Context, baseURL string) (time.Duration, bool) {
+	probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	fullURL, err := c.buildAPIURL(baseURL, "/health", nil)
+	if err != nil {
+		return 0, false
+	}
+	req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, fullURL.String(), nil)
+	if err != nil {
+		return 0, false
+	}
+	req.Header.Set("User-Agent", userAgent)
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.log.Debugf("[%s Breaker] %s 健康检查请求失败: %v", logName, baseURL, err)
+		return 0, false
+	}
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,288 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testServer 模拟的API服务，/health 和其他接口的响应由测试控制
+type testServer struct {
+	*httptest.Server
+	calls   atomic.Int32 // 除 /health 以外的请求次数
+	healthy atomic.Bool
+
+	mu       sync.Mutex
+	statuses []int // 依次返回的状态码，用完后返回最后一个
+}
+
+func newTestServer(t *testing.T, statuses ...int) *testServer {
+	t.Helper()
+	s := &testServer{statuses: statuses}
+	s.healthy.Store(true)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if !s.healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		s.calls.Add(1)
+		status := s.nextStatus()
+		w.WriteHeader(status)
+		if status >= 400 {
+			_, _ = w.Write([]byte(`{"status":"error","message":"模拟错误"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) nextStatus() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statuses[0]
+	if len(s.statuses) > 1 {
+		s.statuses = s.statuses[1:]
+	}
+	return status
+}
+
+func (s *testServer) setStatuses(statuses ...int) {
+	s.mu.Lock()
+	s.statuses = statuses
+	s.mu.Unlock()
+}
+
+// newTestClient 创建重试等待很短的客户端
+func newTestClient(t *testing.T, baseURL string, maxRetries, breakerThreshold int, breakerCooldown time.Duration) *Client {
+	t.Helper()
+	conf := DefaultConfig()
+	conf.BaseURL = baseURL
+	conf.HealthCheckInterval = time.Hour
+	conf.MaxRetries = maxRetries
+	conf.RetryBaseDelay = time.Millisecond
+	conf.RetryMaxDelay = 5 * time.Millisecond
+	conf.BreakerFailureThreshold = breakerThreshold
+	conf.BreakerCooldown = breakerCooldown
+	conf.DisableClientFallback = true
+	c := NewClient(conf)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestRetryDelay(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+	for attempt := 1; attempt <= 10; attempt++ {
+		backoff := base << (attempt - 1)
+		if backoff > max {
+			backoff = max
+		}
+		for i := 0; i < 20; i++ {
+			d := retryDelay(attempt, base, max, 0)
+			if d < backoff/2 || d >= backoff {
+				t.Fatalf("第 %d 次重试的等待时间为 %v，期望在 [%v, %v) 之间", attempt, d, backoff/2, backoff)
+			}
+		}
+	}
+	if d := retryDelay(1, base, max, 3*time.Second); d != 3*time.Second {
+		t.Fatalf("Retry-After 更长时等待 %v，期望 3s", d)
+	}
+	if d := retryDelay(100, base, max, 0); d < max/2 || d >= max {
+		t.Fatalf("退避溢出时等待 %v，期望不超过上限 %v", d, max)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("2"); d != 2*time.Second {
+		t.Fatalf("parseRetryAfter(\"2\") = %v", d)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(future); d <= 50*time.Second || d > time.Minute {
+		t.Fatalf("parseRetryAfter(%q) = %v", future, d)
+	}
+	for _, v := range []string{"", "0", "-1", "abc", "Mon, 01 Jan 2001 00:00:00 GMT"} {
+		if d := parseRetryAfter(v); d != 0 {
+			t.Fatalf("parseRetryAfter(%q) = %v，期望 0", v, d)
+		}
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	s := newTestServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	c := newTestClient(t, s.URL, 2, 0, time.Second)
+	if _, err := c.Search(context.Background(), SearchOptions{Keyword: "k"}); err != nil {
+		t.Fatalf("重试后应当成功: %v", err)
+	}
+	if n := s.calls.Load(); n != 3 {
+		t.Fatalf("请求了 %d 次，期望 3 次", n)
+	}
+}
+
+func TestRetryLimit(t *testing.T) {
+	s := newTestServer(t, http.StatusBadGateway)
+	c := newTestClient(t, s.URL, 2, 0, time.Second)
+	_, err := c.Search(context.Background(), SearchOptions{Keyword: "k"})
+	if CodeOf(err) != ErrCodeBackendUnavailable {
+		t.Fatalf("重试用尽后返回 %v，期望 %s", err, ErrCodeBackendUnavailable)
+	}
+	if n := s.calls.Load(); n != 3 {
+		t.Fatalf("请求了 %d 次，期望 1 次加 2 次重试", n)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		call   func(c *Client) error
+	}{
+		{"GET 404", http.StatusNotFound, func(c *Client) error {
+			_, err := c.Detail(context.Background(), "350234")
+			return err
+		}},
+		{"GET 500", http.StatusInternalServerError, func(c *Client) error {
+			_, err := c.Job(context.Background(), "job1")
+			return err
+		}},
+		{"POST 503", http.StatusServiceUnavailable, func(c *Client) error {
+			_, err := c.Download(context.Background(), "350234", nil)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.status)
+			c := newTestClient(t, s.URL, 3, 0, time.Second)
+			if err := tt.call(c); err == nil {
+				t.Fatal("请求应当失败")
+			}
+			if n := s.calls.Load(); n != 1 {
+				t.Fatalf("请求了 %d 次，期望不重试", n)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	s := newTestServer(t, http.StatusServiceUnavailable)
+	conf := DefaultConfig()
+	conf.BaseURL = s.URL
+	conf.MaxRetries = 5
+	conf.RetryBaseDelay = time.Hour
+	conf.RetryMaxDelay = time.Hour
+	conf.BreakerFailureThreshold = 0
+	conf.DisableClientFallback = true
+	c := NewClient(conf)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Search(ctx, SearchOptions{Keyword: "k"}); err == nil {
+		t.Fatal("请求应当失败")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("剩余时间不足以等待重试时应直接返回")
+	}
+	if n := s.calls.Load(); n != 1 {
+		t.Fatalf("请求了 %d 次，期望 1 次", n)
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	s := newTestServer(t, http.StatusServiceUnavailable)
+	c := newTestClient(t, s.URL, 0, 2, cooldown)
+	b := c.backends.list()[0]
+	search := func() error {
+		_, err := c.Search(context.Background(), SearchOptions{Keyword: "k"})
+		return err
+	}
+
+	// closed: 连续失败未达到阈值
+	_ = search()
+	if b.breaker.isOpen() {
+		t.Fatal("失败 1 次后熔断器不应打开")
+	}
+	// closed -> open
+	_ = search()
+	if !b.breaker.isOpen() {
+		t.Fatal("连续失败 2 次后熔断器应当打开")
+	}
+	// open: 冷却期内不再请求后端
+	if err := search(); !errors.Is(err, errBackendUnavailable) {
+		t.Fatalf("熔断器打开时返回 %v，期望 errBackendUnavailable", err)
+	}
+	if n := s.calls.Load(); n != 2 {
+		t.Fatalf("熔断器打开后仍请求了后端，共 %d 次", n)
+	}
+
+	// half-open: 冷却期过后探测 /health，探测失败则保持打开
+	s.healthy.Store(false)
+	time.Sleep(cooldown + 10*time.Millisecond)
+	if err := search(); !errors.Is(err, errBackendUnavailable) {
+		t.Fatalf("探测失败时返回 %v，期望 errBackendUnavailable", err)
+	}
+	if !b.breaker.isOpen() || s.calls.Load() != 2 {
+		t.Fatal("探测失败后熔断器应保持打开且不请求后端")
+	}
+
+	// half-open -> closed: 探测成功后恢复请求
+	s.healthy.Store(true)
+	s.setStatuses(http.StatusOK)
+	time.Sleep(cooldown + 10*time.Millisecond)
+	if err := search(); err != nil {
+		t.Fatalf("探测成功后请求应当成功: %v", err)
+	}
+	if b.breaker.isOpen() || s.calls.Load() != 3 {
+		t.Fatal("探测成功后熔断器应当关闭")
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	var probes atomic.Int32
+	b := &circuitBreaker{
+		baseURL:   "http://backend",
+		threshold: 1,
+		cooldown:  time.Millisecond,
+		log:       nopLogger{},
+		probe: func(ctx context.Context, baseURL string) (time.Duration, bool) {
+			probes.Add(1)
+			<-release
+			return time.Millisecond, true
+		},
+	}
+	b.record(true)
+	time.Sleep(2 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- b.allow(context.Background()) }()
+	for probes.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 探测进行中的其他请求直接被拒绝
+	if err := b.allow(context.Background()); !errors.Is(err, errBackendUnavailable) {
+		t.Fatalf("探测期间返回 %v，期望 errBackendUnavailable", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("探测成功后 allow 返回 %v", err)
+	}
+	if probes.Load() != 1 || b.isOpen() {
+		t.Fatal("应当只探测一次并关闭熔断器")
+	}
+	// 成功的请求重置连续失败次数
+	b.record(false)
+	if b.failures != 0 {
+		t.Fatalf("failures = %d，期望 0", b.failures)
+	}
+}
@@ -0,0 +1,533 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildAPIURL 根据后端的基础URL拼接接口地址和查询参数
+func (c *Client) buildAPIURL(baseURL, endpoint string, queryParams map[string]string) (*url.URL, error) {
+	fullURL, err := url.Parse(baseURL)
+	if err != nil {
+		c.log.Errorf("[%s API Call] 解析基础URL '%s' 失败: %v", logName, baseURL, err)
+		return nil, fmt.Errorf("无效的API基础URL: %w", err)
+	}
+	// endpoint 是 apiPath 转义过的路径，同时设置 RawPath 避免转义字符被再次编码或还原
+	rawPath := strings.TrimRight(fullURL.EscapedPath(), "/") + "/" + strings.TrimLeft(endpoint, "/")
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, fmt.Errorf("无效的接口路径 '%s': %w", endpoint, err)
+	}
+	fullURL.Path, fullURL.RawPath = path, rawPath
+
+	q := fullURL.Query()
+	if queryParams != nil {
+		for k, v := range queryParams {
+			q.Set(k, v)
+		}
+	}
+	if q.Get("client_type") == "" {
+		q.Set("client_type", c.clientTypes.current())
+	}
+	fullURL.RawQuery = q.Encode()
+	return fullURL, nil
+}
+
+// makeAPIRequest 发起HTTP请求到Python API服务，endpoint 应由 apiPath 生成
+// 幂等的GET请求在网络错误、429和5xx网关错误时按指数退避重试；
+// 其他方法 (如提交下载任务的POST) 只尝试一次，避免重复提交
+func (c *Client) makeAPIRequest(ctx context.Context, method, endpoint string, queryParams map[string]string, body interface{}) (*apiResponse, error) {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		jsonBody, err = json.Marshal(body)
+		if err != nil {
+			c.log.Errorf("[%s API Call] 序列化请求体失败: %v", logName, err)
+			return nil, fmt.Errorf("序列化请求体失败: %w", err)
+		}
+	}
+
+	maxAttempts := 1
+	if method == http.MethodGet {
+		maxAttempts += c.maxRetries
+	}
+	for attempt := 1; ; attempt++ {
+		apiResp, result := c.requestWithFailover(ctx, method, endpoint, queryParams, jsonBody)
+		if result.err == nil {
+			return apiResp, nil
+		}
+		if attempt >= maxAttempts || !result.retryable || ctx.Err() != nil {
+			return apiResp, result.err
+		}
+
+		delay := retryDelay(attempt, c.retryBaseDelay, c.retryMaxDelay, result.retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// 剩余时间不足以等待重试，直接返回本次的错误
+			return apiResp, result.err
+		}
+		c.log.Warnf("[%s API Call] %s %s 第 %d 次请求失败，%v 后重试: %v", logName, method, endpoint, attempt, delay, result.err)
+		if !sleepContext(ctx, delay) {
+			return apiResp, result.err
+		}
+	}
+}
+
+// requestWithFailover 按选择策略依次尝试各个后端，返回第一个成功的响应
+// GET请求在连接错误和5xx时换下一个后端；其他方法只在连接未建立时切换，避免重复提交
+func (c *Client) requestWithFailover(ctx context.Context, method, endpoint string, queryParams map[string]string, jsonBody []byte) (*apiResponse, attemptResult) {
+	candidates, err := c.backends.candidates(ctx)
+	if err != nil {
+		return nil, attemptResult{err: err}
+	}
+	var apiResp *apiResponse
+	result := attemptResult{err: errBackendUnavailable, retryable: true}
+	for i, b := range candidates {
+		if i > 0 {
+			c.log.Warnf("[%s API Call] %s %s 切换到后端 %s: %v", logName, method, endpoint, b.url, result.err)
+		}
+		if err := b.breaker.allow(ctx); err != nil {
+			result = attemptResult{err: err, retryable: true}
+			continue
+		}
+		fullURL, err := c.buildAPIURL(b.url, endpoint, queryParams)
+		if err != nil {
+			return nil, attemptResult{err: err}
+		}
+		start := time.Now()
+		apiResp, result = c.doAPIRequest(ctx, method, fullURL.String(), jsonBody)
+		b.breaker.record(result.backendFailure)
+		b.observe(time.Since(start), result.backendFailure, result.err)
+		if result.err == nil {
+			apiResp.backend = b.url
+			return apiResp, result
+		}
+		if ctx.Err() != nil || !canFailover(method, result) {
+			return apiResp, result
+		}
+	}
+	return apiResp, result
+}
+
+// makeClientRequest 发起依赖JM客户端的GET请求 (搜索、详情)
+// 自动模式下，当前客户端类型在API服务端出错 (HTTP 5xx) 时改用另一种类型重试一次
+func (c *Client) makeClientRequest(ctx context.Context, endpoint string, queryParams map[string]string) (*apiResponse, error) {
+	clientType := c.clientTypes.current()
+	params := make(map[string]string, len(queryParams)+1)
+	for k, v := range queryParams {
+		params[k] = v
+	}
+	params["client_type"] = clientType
+	c.log.Debugf("[%s Client] %s 使用 %s 客户端", logName, endpoint, clientType)
+
+	apiResp, err := c.makeAPIRequest(ctx, http.MethodGet, endpoint, params, nil)
+	if err == nil || apiResp == nil || apiResp.statusCode < 500 || !c.clientTypes.canFallback() || ctx.Err() != nil {
+		return apiResp, err
+	}
+
+	alternate := alternateClient(clientType)
+	params["client_type"] = alternate
+	c.log.Debugf("[%s Client] %s 使用 %s 客户端失败，改用 %s 客户端重试: %v", logName, endpoint, clientType, alternate, err)
+	altResp, altErr := c.makeAPIRequest(ctx, http.MethodGet, endpoint, params, nil)
+	if altErr != nil {
+		// 两种客户端都失败时返回原来的错误
+		return apiResp, err
+	}
+	c.clientTypes.recordFallback(clientType, alternate)
+	return altResp, nil
+}
+
+// canFailover 判断失败的请求能否改发到其他后端
+func canFailover(method string, result attemptResult) bool {
+	if method == http.MethodGet {
+		return result.backendFailure
+	}
+	return result.notSent
+}
+
+// attemptResult 单次请求的结果，用于决定是否重试以及是否计入熔断
+type attemptResult struct {
+	err            error
+	retryable      bool          // 是否值得重试 (网络错误、429、502/503/504)
+	backendFailure bool          // 是否视为后端故障 (网络错误、5xx)，计入熔断器
+	retryAfter     time.Duration // 响应中 Retry-After 指定的等待时间
+	notSent        bool          // 连接未建立，请求确定没有到达后端
+}
+
+// doAPIRequest 执行一次HTTP请求并解析响应
+func (c *Client) doAPIRequest(ctx context.Context, method, fullURL string, jsonBody []byte) (*apiResponse, attemptResult) {
+	var reqBody io.Reader
+	if jsonBody != nil {
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		c.log.Errorf("[%s API Call] 创建HTTP请求失败: %v", logName, err)
+		return nil, attemptResult{err: fmt.Errorf("创建HTTP请求失败: %w", err)}
+	}
+	if jsonBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	c.log.Debugf("[%s API Call] Request: %s %s", logName, method, fullURL)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.log.Errorf("[%s API Call] HTTP请求执行失败 (%s %s): %v", logName, method, fullURL, err)
+		// 调用方取消或超时不算后端故障
+		failed := ctx.Err() == nil
+		return nil, attemptResult{err: newTransportError(ctx, err), retryable: failed, backendFailure: failed, notSent: failed && isDialError(err)}
+	}
+	defer resp.Body.Close()
+
+	result := attemptResult{
+		retryable:      isRetryableStatus(resp.StatusCode),
+		backendFailure: resp.StatusCode >= 500,
+		retryAfter:     parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Errorf("[%s API Call] 读取响应体失败: %v", logName, err)
+		result.err = newTransportError(ctx, err)
+		result.retryable = ctx.Err() == nil
+		return nil, result
+	}
+	c.log.Debugf("[%s API Call] Response Status: %s, Body: %s", logName, resp.Status, string(respBody))
+
+	apiResp := apiResponse{statusCode: resp.StatusCode}
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		c.log.Errorf("[%s API Call] 解析API响应JSON失败: %v. Raw body: %s", logName, err, string(respBody))
+		result.err = &APIError{Code: errorCodeFromStatus(resp.StatusCode), StatusCode: resp.StatusCode, Message: "无法解析API响应", Err: err}
+		return &apiResponse{Status: "error", Message: fmt.Sprintf("无法解析API响应 (HTTP %d): %s", resp.StatusCode, string(respBody)), statusCode: resp.StatusCode}, result
+	}
+
+	if resp.StatusCode >= 400 {
+		c.log.Errorf("[%s API Call] API返回HTTP错误 %d [%s]: %s", logName, resp.StatusCode, apiResp.Code, apiResp.Message)
+		result.err = newStatusError(resp.StatusCode, apiResp.Code, apiResp.Message)
+		return &apiResp, result
+	}
+
+	if apiResp.Status == "error" {
+		c.log.Warnf("[%s API Call] API业务逻辑错误 [%s]: %s", logName, apiResp.Code, apiResp.Message)
+		result.err = newStatusError(resp.StatusCode, apiResp.Code, apiResp.Message)
+		return &apiResp, result
+	}
+
+	return &apiResp, result
+}
+
+// Search 调用API按条件搜索漫画，opts.Page <= 0 时搜索第一页
+func (c *Client) Search(ctx context.Context, opts SearchOptions) (*SearchPage, error) {
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	page := opts.Page
+	params := map[string]string{"keyword": opts.Keyword, "page": strconv.Itoa(page)}
+	if opts.Sort != "" {
+		params["sort"] = opts.Sort
+	}
+	if opts.Time != "" {
+		params["time"] = opts.Time
+	}
+	if opts.Category != "" {
+		params["category"] = opts.Category
+	}
+	if opts.MainTag != "" {
+		params["main_tag"] = opts.MainTag
+	}
+	apiResp, err := c.makeClientRequest(ctx, "/search", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchPage{Options: opts}
+	if err := json.Unmarshal(apiResp.Data, &result.Items); err != nil {
+		c.log.Errorf("[%s Service] 解析搜索结果数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析搜索结果失败: %w", err)
+	}
+	if len(apiResp.Meta) > 0 {
+		if err := json.Unmarshal(apiResp.Meta, &result.SearchMeta); err != nil {
+			c.log.Warnf("[%s Service] 解析搜索分页信息失败: %v", logName, err)
+		}
+	}
+	// 旧版API服务不返回分页信息时，按只有一页处理
+	if result.Page <= 0 {
+		result.Page = page
+	}
+	if result.PageSize <= 0 {
+		result.PageSize = len(result.Items)
+	}
+	if result.Total < len(result.Items) {
+		result.Total = len(result.Items)
+	}
+	if result.PageCount < result.Page {
+		result.PageCount = result.Page
+	}
+	return result, nil
+}
+
+// Detail 调用API获取漫画详情
+func (c *Client) Detail(ctx context.Context, albumID string) (*ComicDetail, error) {
+	id, err := ParseAlbumID(albumID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := apiPath("comic", string(id))
+	apiResp, err := c.makeClientRequest(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail ComicDetail
+	if err := json.Unmarshal(apiResp.Data, &detail); err != nil {
+		c.log.Errorf("[%s Service] 解析详情数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析漫画详情失败: %w", err)
+	}
+	return &detail, nil
+}
+
+// Photo 调用API获取章节 (photo) 的基本信息，主要用于找到章节所属的漫画
+func (c *Client) Photo(ctx context.Context, photoID string) (*PhotoInfo, error) {
+	id, err := ParsePhotoID(photoID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := apiPath("photo", string(id))
+	apiResp, err := c.makeClientRequest(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var photo PhotoInfo
+	if err := json.Unmarshal(apiResp.Data, &photo); err != nil {
+		c.log.Errorf("[%s Service] 解析章节数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析章节信息失败: %w", err)
+	}
+	return &photo, nil
+}
+
+// ChapterPages 调用API获取章节 (photo) 前 limit 页的图片信息
+func (c *Client) ChapterPages(ctx context.Context, photoID string, limit int) ([]PageImage, error) {
+	id, err := ParsePhotoID(photoID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := apiPath("photo", string(id), "pages")
+	params := map[string]string{"limit": strconv.Itoa(limit)}
+	apiResp, err := c.makeAPIRequest(ctx, http.MethodGet, endpoint, params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []PageImage
+	if err := json.Unmarshal(apiResp.Data, &pages); err != nil {
+		c.log.Errorf("[%s Service] 解析章节页面数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析章节页面失败: %w", err)
+	}
+	return pages, nil
+}
+
+// PageImage 通过API服务获取还原后的章节页面图片
+func (c *Client) PageImage(ctx context.Context, photoID string, pageIndex int, maxBytes int64) ([]byte, error) {
+	id, err := ParsePhotoID(photoID)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	endpoint := apiPath("photo", string(id), "pages", strconv.Itoa(pageIndex))
+	if _, err := c.fetchRaw(ctx, endpoint, &buf, maxBytes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Download 调用API提交章节下载任务，chapterIDs 为空时下载漫画的全部章节
+// API服务会立即返回任务ID，下载在服务器后台进行，可通过 Job 轮询进度或使用 WatchJob
+func (c *Client) Download(ctx context.Context, albumID string, chapterIDs []string) (*JobStatus, error) {
+	id, err := ParseAlbumID(albumID)
+	if err != nil {
+		return nil, err
+	}
+	reqBody := downloadRequest{ChapterIDs: make([]string, 0, len(chapterIDs))}
+	for _, chapterID := range chapterIDs {
+		photoID, err := ParsePhotoID(chapterID)
+		if err != nil {
+			return nil, err
+		}
+		reqBody.ChapterIDs = append(reqBody.ChapterIDs, string(photoID))
+	}
+	endpoint := apiPath("download", string(id))
+
+	apiResp, err := c.makeAPIRequest(ctx, http.MethodPost, endpoint, nil, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var status JobStatus
+	if err := json.Unmarshal(apiResp.Data, &status); err != nil {
+		c.log.Errorf("[%s Service] 解析下载任务数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析下载任务失败: %w", err)
+	}
+	if status.JobID == "" {
+		return nil, fmt.Errorf("API未返回下载任务ID")
+	}
+	// 任务ID会用作本地目录名，与其他任务接口一样先校验
+	if err := checkJobID(status.JobID); err != nil {
+		return nil, fmt.Errorf("API返回的下载任务ID无效: %w", err)
+	}
+	if status.Message == "" {
+		status.Message = apiResp.Message
+	}
+	if status.DownloadPathHint == "" {
+		status.DownloadPathHint = apiResp.DownloadPathHint
+	}
+	status.Backend = apiResp.backend
+	return &status, nil
+}
+
+// Job 调用API查询下载任务状态
+func (c *Client) Job(ctx context.Context, jobID string) (*JobStatus, error) {
+	if err := checkJobID(jobID); err != nil {
+		return nil, err
+	}
+	endpoint := apiPath("jobs", jobID)
+	apiResp, err := c.makeAPIRequest(ctx, http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status JobStatus
+	if err := json.Unmarshal(apiResp.Data, &status); err != nil {
+		c.log.Errorf("[%s Service] 解析任务状态数据失败: %v", logName, err)
+		return nil, fmt.Errorf("解析任务状态失败: %w", err)
+	}
+	return &status, nil
+}
+
+// JobFiles 调用API列出已完成任务下载得到的文件
+func (c *Client) JobFiles(ctx context.Context, jobID string) ([]JobFile, error) {
+	if err := checkJobID(jobID); err != nil {
+		return nil, err
+	}
+	endpoint := apiPath("jobs", jobID, "files")
+	apiResp, err := c.makeAPIRequest(ctx, http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []JobFile
+	if err := json.Unmarshal(apiResp.Data, &files); err != nil {
+		c.log.Errorf("[%s Service] 解析任务文件列表失败: %v", logName, err)
+		return nil, fmt.Errorf("解析任务文件列表失败: %w", err)
+	}
+	return files, nil
+}
+
+// FetchJobFile 从API服务下载任务中的单个文件并写入 dst
+// 文件内容超过 maxBytes 时返回错误 (maxBytes <= 0 表示不限制)
+func (c *Client) FetchJobFile(ctx context.Context, jobID string, fileIndex int, dst io.Writer, maxBytes int64) (int64, error) {
+	if err := checkJobID(jobID); err != nil {
+		return 0, err
+	}
+	return c.fetchRaw(ctx, apiPath("jobs", jobID, "files", strconv.Itoa(fileIndex)), dst, maxBytes)
+}
+
+// Cover 通过API服务获取漫画封面图片并写入 dst
+func (c *Client) Cover(ctx context.Context, albumID string, dst io.Writer, maxBytes int64) (int64, error) {
+	id, err := ParseAlbumID(albumID)
+	if err != nil {
+		return 0, err
+	}
+	return c.fetchRaw(ctx, apiPath("cover", string(id)), dst, maxBytes)
+}
+
+// fetchRaw 请求返回二进制内容 (图片、文件) 的接口，并将内容写入 dst
+// 出错时接口返回的是JSON错误信息，会被解析为错误消息
+// 内容开始写入 dst 之前的连接错误和5xx会切换到下一个后端，写入后不再重试
+func (c *Client) fetchRaw(ctx context.Context, endpoint string, dst io.Writer, maxBytes int64) (int64, error) {
+	candidates, err := c.backends.candidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	lastErr := errBackendUnavailable
+	for i, b := range candidates {
+		if i > 0 {
+			c.log.Warnf("[%s API Call] GET %s 切换到后端 %s: %v", logName, endpoint, b.url, lastErr)
+		}
+		if err := b.breaker.allow(ctx); err != nil {
+			lastErr = err
+			continue
+		}
+		n, failover, err := c.fetchRawFrom(ctx, b, endpoint, dst, maxBytes)
+		if err == nil || !failover || ctx.Err() != nil {
+			return n, err
+		}
+		lastErr = err
+	}
+	return 0, lastErr
+}
+
+// fetchRawFrom 从指定后端请求二进制内容，failover 表示内容尚未写入且可以换后端重试
+func (c *Client) fetchRawFrom(ctx context.Context, b *backend, endpoint string, dst io.Writer, maxBytes int64) (n int64, failover bool, err error) {
+	fullURL, err := c.buildAPIURL(b.url, endpoint, nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL.String(), nil)
+	if err != nil {
+		c.log.Errorf("[%s API Call] 创建HTTP请求失败: %v", logName, err)
+		return 0, false, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	c.log.Debugf("[%s API Call] Request: GET %s", logName, fullURL.String())
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.log.Errorf("[%s API Call] HTTP请求执行失败 (GET %s): %v", logName, fullURL.String(), err)
+		err = newTransportError(ctx, err)
+		failed := ctx.Err() == nil
+		b.breaker.record(failed)
+		b.observe(time.Since(start), failed, err)
+		return 0, failed, err
+	}
+	defer resp.Body.Close()
+	failed := resp.StatusCode >= 500
+	b.breaker.record(failed)
+
+	if resp.StatusCode >= 400 {
+		var apiResp apiResponse
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = json.Unmarshal(body, &apiResp)
+		err = newStatusError(resp.StatusCode, apiResp.Code, apiResp.Message)
+		b.observe(time.Since(start), failed, err)
+		return 0, failed, err
+	}
+	b.observe(time.Since(start), false, nil)
+
+	reader := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		// 多读一个字节用于判断是否超限
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	n, err = io.Copy(dst, reader)
+	if err != nil {
+		return n, false, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+	if maxBytes > 0 && n > maxBytes {
+		return n, false, fmt.Errorf("文件大小超过限制 (%d 字节)", maxBytes)
+	}
+	return n, false, nil
+}
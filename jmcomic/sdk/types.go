@@ -0,0 +1,141 @@
+package sdk
+
+import "encoding/json"
+
+// apiResponse Python API服务的通用响应结构
+type apiResponse struct {
+	Status           string          `json:"status"` // "success" or "error"
+	Data             json.RawMessage `json:"data,omitempty"`
+	Message          string          `json:"message,omitempty"`
+	Code             string          `json:"code,omitempty"` // 出错时的错误码，见 ErrorCode
+	DownloadPathHint string          `json:"download_path_hint,omitempty"`
+	Meta             json.RawMessage `json:"meta,omitempty"` // 分页等附加信息，如 SearchMeta
+
+	backend    string // 返回该响应的API后端
+	statusCode int    // HTTP状态码
+}
+
+// ComicSearchResultItem 搜索结果中的单个漫画
+type ComicSearchResultItem struct {
+	ID          string `json:"id"`          // 漫画 (album) ID，纯数字
+	Title       string `json:"title"`       // 标题
+	Author      string `json:"author"`      // 作者，多个作者以 ", " 分隔
+	Tags        string `json:"tags"`        // 标签，以 ", " 分隔
+	Description string `json:"description"` // 简介，没有时为 "N/A"
+	CoverURL    string `json:"cover_url"`   // 封面的原始地址，可能无法直接访问，应通过 Client.Cover 获取
+	SourceSite  string `json:"source_site"` // 数据来源站点
+}
+
+// SearchOptions 搜索条件，空字符串表示使用API服务的默认值
+type SearchOptions struct {
+	Keyword  string
+	Page     int    // 从1开始，<= 0 时为第一页
+	Sort     string // 排序: latest, views, pictures, likes
+	Time     string // 时间范围: all, today, week, month
+	Category string // 分类: all, doujin, single, short, another, hanman, meiman, cosplay, 3d
+	MainTag  string // 搜索范围: site, work, author, tag, actor
+}
+
+// SearchMeta 搜索结果的分页信息
+type SearchMeta struct {
+	Page      int `json:"page"`
+	PageSize  int `json:"page_size"`  // 本页结果数
+	Total     int `json:"total"`      // 所有页的结果总数
+	PageCount int `json:"page_count"` // 总页数
+}
+
+// SearchPage 一页搜索结果
+type SearchPage struct {
+	Options SearchOptions // 本页对应的搜索条件，翻页时沿用
+	Items   []ComicSearchResultItem
+	SearchMeta
+}
+
+// HasNextPage 判断是否还有下一页
+func (p *SearchPage) HasNextPage() bool {
+	return p.Page < p.PageCount
+}
+
+// ChapterInfo 漫画的一个章节 (photo)
+type ChapterInfo struct {
+	ID        string `json:"id"`         // 章节 (photo) ID，提交下载时使用
+	Title     string `json:"title"`      // 章节标题
+	Index     string `json:"index"`      // 章节序号，从 "1" 开始，未知时为 "N/A"
+	PageCount int    `json:"page_count"` // 页数，未知时为0
+}
+
+// PageImage 章节中单页图片的信息
+type PageImage struct {
+	Index    int    `json:"index"`    // 页码，从0开始，用于 Client.PageImage
+	Filename string `json:"filename"` // 图片文件名
+	URL      string `json:"url"`      // 原始CDN地址，图片可能被切割打乱，应通过 Client.PageImage 获取
+}
+
+// ComicDetail 漫画详细信息
+type ComicDetail struct {
+	ID          string        `json:"id"`          // 漫画 (album) ID
+	Title       string        `json:"title"`       // 标题
+	Author      string        `json:"author"`      // 作者，多个作者以 ", " 分隔
+	Tags        string        `json:"tags"`        // 标签，以 ", " 分隔
+	Description string        `json:"description"` // 简介
+	CoverURL    string        `json:"cover_url"`   // 封面的原始地址
+	Chapters    []ChapterInfo `json:"chapters"`    // 章节列表，按章节顺序排列
+	SourceSite  string        `json:"source_site"` // 数据来源站点
+}
+
+// PhotoInfo 章节 (photo) 的基本信息，用于由章节ID找到所属的漫画
+type PhotoInfo struct {
+	ID      string `json:"id"`       // 章节 (photo) ID
+	AlbumID string `json:"album_id"` // 所属漫画 (album) ID，单章节漫画与章节ID相同
+	Title   string `json:"title"`    // 章节标题
+	Index   int    `json:"index"`    // 章节在漫画中的序号，从1开始，未知时为0
+}
+
+// downloadRequest 提交下载任务的请求体
+type downloadRequest struct {
+	ChapterIDs []string `json:"chapter_ids"`
+}
+
+// JobState 下载任务所处的阶段
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"    // 已提交，等待执行
+	JobStateRunning   JobState = "running"   // 正在下载
+	JobStateCompleted JobState = "completed" // 下载完成
+	JobStateFailed    JobState = "failed"    // 下载失败
+)
+
+// IsTerminal 判断任务是否已结束 (完成或失败)
+func (s JobState) IsTerminal() bool {
+	return s == JobStateCompleted || s == JobStateFailed
+}
+
+// JobStatus API服务返回的下载任务状态
+type JobStatus struct {
+	JobID            string    `json:"job_id"`
+	AlbumID          string    `json:"album_id"`
+	ChapterIDs       []string  `json:"chapter_ids"`
+	State            JobState  `json:"state"`
+	Message          string    `json:"message,omitempty"`
+	Error            string    `json:"error,omitempty"`      // 任务失败时的原始错误信息
+	ErrorCode        string    `json:"error_code,omitempty"` // 任务失败时的错误码，见 ErrorCode
+	DownloadPathHint string    `json:"download_path_hint,omitempty"`
+	CreatedAt        float64   `json:"created_at"`  // Unix时间戳 (秒)
+	UpdatedAt        float64   `json:"updated_at"`  // Unix时间戳 (秒)
+	FinishedAt       float64   `json:"finished_at"` // Unix时间戳 (秒)，未结束时为0
+	Files            []JobFile `json:"files,omitempty"`
+
+	// Backend 接收任务的API后端地址，只在 Client.Download 的返回值中设置
+	// 任务只存在于该后端上，查询任务时应通过 WithBackend 固定后端
+	Backend string `json:"-"`
+}
+
+// JobFile 下载任务产生的单个文件
+type JobFile struct {
+	Index        int    `json:"index"`         // 文件在任务中的下标，用于 Client.FetchJobFile
+	ChapterID    string `json:"chapter_id"`    // 所属章节 (photo) ID
+	ChapterIndex string `json:"chapter_index"` // 所属章节的序号
+	Name         string `json:"name"`          // 文件名
+	Size         int64  `json:"size"`          // 字节
+}
@@ -0,0 +1,275 @@
+package jmcomic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+// searchOption 一个搜索选项的可选值，values 的键为命令中使用的名称，值为中文说明
+type searchOption struct {
+	flag   string
+	label  string
+	order  []string // 用法提示中的显示顺序
+	values map[string]string
+}
+
+var (
+	searchSortOption = searchOption{
+		flag: "sort", label: "排序",
+		order:  []string{"latest", "views", "pictures", "likes"},
+		values: map[string]string{"latest": "最新", "views": "最多浏览", "pictures": "最多图片", "likes": "最多喜欢"},
+	}
+	searchTimeOption = searchOption{
+		flag: "time", label: "时间范围",
+		order:  []string{"all", "today", "week", "month"},
+		values: map[string]string{"all": "全部", "today": "今天", "week": "本周", "month": "本月"},
+	}
+	searchCategoryOption = searchOption{
+		flag: "category", label: "分类",
+		order: []string{"all", "doujin", "single", "short", "another", "hanman", "meiman", "cosplay", "3d"},
+		values: map[string]string{
+			"all": "全部", "doujin": "同人", "single": "单本", "short": "短篇", "another": "其他",
+			"hanman": "韩漫", "meiman": "美漫", "cosplay": "Cosplay", "3d": "3D",
+		},
+	}
+	searchMainTagOption = searchOption{
+		flag: "by", label: "搜索范围",
+		order:  []string{"site", "work", "author", "tag", "actor"},
+		values: map[string]string{"site": "站内", "work": "作品", "author": "作者", "tag": "标签", "actor": "登场人物"},
+	}
+)
+
+// parse 校验选项值 (不区分大小写)，无效时返回列出所有可选值的错误
+func (o searchOption) parse(value string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if _, ok := o.values[v]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("无效的%s '%s'。--%s 可选值:\n%s", o.label, value, o.flag, o.usage())
+}
+
+// usage 返回可选值列表，例如 "latest(最新), views(最多浏览)"
+func (o searchOption) usage() string {
+	items := make([]string, 0, len(o.order))
+	for _, v := range o.order {
+		items = append(items, fmt.Sprintf("%s(%s)", v, o.values[v]))
+	}
+	return strings.Join(items, ", ")
+}
+
+// searchUsage 列出所有搜索选项及可选值
+func searchUsage() string {
+	lines := []string{"可用选项:"}
+	for i, o := range []searchOption{searchSortOption, searchTimeOption, searchCategoryOption, searchMainTagOption} {
+		lines = append(lines, fmt.Sprintf("--%s (-%s) %s: %s", o.flag, searchFlags[i].short, o.label, o.usage()))
+	}
+	lines = append(lines, "--page (-p) 页码: 正整数")
+	return strings.Join(lines, "\n")
+}
+
+// searchFlags jm search 的选项
+var searchFlags = []flagSpec{
+	{name: searchSortOption.flag, short: "s", value: searchSortOption.label},
+	{name: searchTimeOption.flag, short: "t", value: searchTimeOption.label},
+	{name: searchCategoryOption.flag, short: "c", value: searchCategoryOption.label},
+	{name: searchMainTagOption.flag, short: "b", value: searchMainTagOption.label},
+	{name: "page", short: "p", value: "页码"},
+}
+
+// parseSearchOptions 解析 jm search 的参数: 关键词和 --page/--sort/--time/--category/--by 选项
+// 关键词可以用引号包围，例如 "full metal"
+func parseSearchOptions(args []string) (SearchOptions, error) {
+	opts := SearchOptions{Page: 1}
+	rest, flags, err := parseFlags(args, searchFlags)
+	if err != nil {
+		return opts, err
+	}
+	opts.Keyword = strings.TrimSpace(strings.Join(rest, " "))
+	if opts.Keyword == "" {
+		return opts, fmt.Errorf("请输入搜索关键词！例如: %s search 老师 --sort views --time week\n%s", cmdPrefix, searchUsage())
+	}
+
+	if value, ok := flags["page"]; ok {
+		page, err := strconv.Atoi(value)
+		if err != nil || page <= 0 {
+			return opts, fmt.Errorf("页码 '%s' 无效，请输入正整数。", value)
+		}
+		opts.Page = page
+	}
+	for _, item := range []struct {
+		option searchOption
+		target *string
+	}{
+		{searchSortOption, &opts.Sort},
+		{searchTimeOption, &opts.Time},
+		{searchCategoryOption, &opts.Category},
+		{searchMainTagOption, &opts.MainTag},
+	} {
+		value, ok := flags[item.option.flag]
+		if !ok {
+			continue
+		}
+		if *item.target, err = item.option.parse(value); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// describeSearchOptions 生成搜索条件的简短描述，例如 "老师 (最多浏览, 本周, 第 2 页)"
+func describeSearchOptions(opts SearchOptions) string {
+	var parts []string
+	if opts.Sort != "" {
+		parts = append(parts, searchSortOption.values[opts.Sort])
+	}
+	if opts.Time != "" {
+		parts = append(parts, searchTimeOption.values[opts.Time])
+	}
+	if opts.Category != "" {
+		parts = append(parts, searchCategoryOption.values[opts.Category])
+	}
+	if opts.MainTag != "" {
+		parts = append(parts, "按"+searchMainTagOption.values[opts.MainTag])
+	}
+	if opts.Page > 1 {
+		parts = append(parts, fmt.Sprintf("第 %d 页", opts.Page))
+	}
+	keyword := sanitizeText(opts.Keyword, maxTitleRunes)
+	if len(parts) == 0 {
+		return keyword
+	}
+	return fmt.Sprintf("%s (%s)", keyword, strings.Join(parts, ", "))
+}
+
+// searchState 用户最近一次搜索的状态，用于 jm more 继续浏览
+type searchState struct {
+	page   *SearchPage
+	offset int // 本页中下一条待显示结果的下标
+}
+
+// searchStateStore 按群和用户记录最近一次搜索
+type searchStateStore struct {
+	mu     sync.Mutex
+	states map[sessionKey]*searchState
+}
+
+var searchStates = &searchStateStore{states: make(map[sessionKey]*searchState)}
+
+// put 记录一页新的搜索结果，从本页开头开始显示
+func (s *searchStateStore) put(key sessionKey, page *SearchPage) *searchState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := &searchState{page: page}
+	s.states[key] = state
+	return state
+}
+
+func (s *searchStateStore) get(key sessionKey) (*searchState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	return state, ok
+}
+
+// next 取出下一批待显示的结果并推进偏移，返回这批结果、第一条的序号 (从1开始) 以及本页是否还有未显示的结果
+func (s *searchStateStore) next(state *searchState) ([]ComicSearchResultItem, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := state.offset
+	end := start + cfg.MaxSearchResultsDisplay
+	if end > len(state.page.Items) {
+		end = len(state.page.Items)
+	}
+	state.offset = end
+	return state.page.Items[start:end], start + 1, end < len(state.page.Items)
+}
+
+// remaining 返回本页中还未显示的结果数
+func (s *searchStateStore) remaining(state *searchState) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(state.page.Items) - state.offset
+}
+
+// sendSearchResults 发送搜索状态中的下一批结果，并在开启时进入交互式选择
+func sendSearchResults(ctx *zero.Ctx, state *searchState) {
+	items, first, more := searchStates.next(state)
+	page := state.page
+	last := first + len(items) - 1
+
+	var covers map[string]message.Segment
+	if coversEnabled(ctx.Event.GroupID) {
+		ids := make([]string, 0, len(items))
+		for _, comic := range items {
+			ids = append(ids, comic.ID)
+		}
+		coverCtx, coverCancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+		covers = coverSegments(coverCtx, ids)
+		coverCancel()
+	}
+
+	view := searchView{
+		Keyword:       cleanField(page.Options.Keyword, maxTitleRunes),
+		Total:         page.Total,
+		Page:          page.Page,
+		PageCount:     page.PageCount,
+		First:         first,
+		Last:          last,
+		Items:         make([]searchItemView, len(items)),
+		HasMore:       more || page.HasNextPage(),
+		Interactive:   cfg.InteractiveSelection,
+		CancelKeyword: cancelKeyword,
+	}
+	for i, comic := range items {
+		view.Items[i] = searchItemView{Index: first + i, ComicSearchResultItem: comic}
+	}
+	ctx.SendChain(renderChain(tmplSearch, view, covers))
+
+	if cfg.InteractiveSelection {
+		// 会话在后台等待回复，命令处理器立即返回
+		go runSelectionSession(ctx, items, first)
+	}
+}
+
+// handleSearchMore 处理 jm more: 显示上一次搜索的后续结果，本页显示完后自动请求下一页
+func handleSearchMore(ctx *zero.Ctx) {
+	key := sessionKey{groupID: ctx.Event.GroupID, userID: ctx.Event.UserID}
+	state, ok := searchStates.get(key)
+	if !ok {
+		ctx.SendChain(message.Text(fmt.Sprintf("没有最近的搜索记录，请先使用 %s search <关键词> 搜索。", cmdPrefix)))
+		return
+	}
+	if searchStates.remaining(state) > 0 {
+		sendSearchResults(ctx, state)
+		return
+	}
+	if !state.page.HasNextPage() {
+		ctx.SendChain(message.Text(fmt.Sprintf("'%s' 的搜索结果已全部显示。", sanitizeText(state.page.Options.Keyword, maxTitleRunes))))
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), cfg.timeoutDuration)
+	defer cancel()
+
+	opts := state.page.Options
+	opts.Page = state.page.Page + 1
+	ctx.SendChain(message.Text(fmt.Sprintf("正在获取 '%s' 的第 %d 页...", sanitizeText(opts.Keyword, maxTitleRunes), opts.Page)))
+	result, err := api.Search(reqCtx, opts)
+	if err != nil {
+		zlog.Errorf("[%s Handler] 搜索 '%s' 第 %d 页失败: %v", pluginName, opts.Keyword, opts.Page, err)
+		ctx.SendChain(message.Text(renderError("搜索失败", err)))
+		return
+	}
+	if len(result.Items) == 0 {
+		ctx.SendChain(message.Text(fmt.Sprintf("'%s' 的搜索结果已全部显示。", sanitizeText(state.page.Options.Keyword, maxTitleRunes))))
+		return
+	}
+	sendSearchResults(ctx, searchStates.put(key, result))
+}
@@ -0,0 +1,174 @@
+package jmcomic
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+	zero "github.com/FloatTech/zerobot/core"
+)
+
+const cancelKeyword = "取消" // 结束交互式选择会话的回复
+
+// selectionReplyRule 只有序号、章节选择 (如 "1 3 5"、"1-5"、"all"、"last:3") 或 "取消" 的回复才会进入会话
+var selectionReplyRule = zero.RegexRule(`(?i)^\s*(` + selectionItem + `([\s,，、]+` + selectionItem + `)*|` + cancelKeyword + `)\s*$`)
+
+// selectionItem 会话中一个序号或章节选择的写法，具体格式由 parseChapterSelection 检查
+const selectionItem = `(\d+(-\d+)?|all|latest|last:\d+|全部|最新)`
+
+// sessionKey 交互式选择会话按群和用户区分，私聊时 groupID 为0
+type sessionKey struct {
+	groupID int64
+	userID  int64
+}
+
+// selectionSessions 记录每个用户当前的会话，同一用户开始新会话时旧会话会被结束
+var selectionSessions = struct {
+	sync.Mutex
+	stops map[sessionKey]chan struct{}
+}{stops: make(map[sessionKey]chan struct{})}
+
+// beginSession 开始一个会话并结束同一用户在同一会话中的旧会话
+// 返回的 stop 通道在会话被新会话取代时关闭，end 用于会话正常结束时清理
+func beginSession(key sessionKey) (stop <-chan struct{}, end func()) {
+	selectionSessions.Lock()
+	defer selectionSessions.Unlock()
+	if old, ok := selectionSessions.stops[key]; ok {
+		close(old)
+	}
+	ch := make(chan struct{})
+	selectionSessions.stops[key] = ch
+	return ch, func() {
+		selectionSessions.Lock()
+		defer selectionSessions.Unlock()
+		if selectionSessions.stops[key] == ch {
+			delete(selectionSessions.stops, key)
+		}
+	}
+}
+
+// runSelectionSession 搜索结果发送后，等待同一用户回复序号打开对应漫画的详情，
+// 再等待回复章节序号提交下载。回复 "取消" 或超时后会话结束
+// first 为 results[0] 显示的序号，jm more 翻页后序号不从1开始
+// 会话在单独的 goroutine 中运行，不占用发起搜索的命令处理器
+func runSelectionSession(ctx *zero.Ctx, results []ComicSearchResultItem, first int) {
+	if len(results) == 0 {
+		return
+	}
+	defer recoverSession("交互式选择会话")
+	// 会话结束后再提交下载，下载前可能需要回复确认，不能被本会话的回复规则截获
+	// 与直接发送 jm download 一样经过权限、频率限制等中间件
+	if args := awaitSelection(ctx, results, first); args != nil {
+		c, _ := commands.lookup("download")
+		commands.run(ctx, c, args)
+	}
+}
+
+// recoverSession 记录会话 goroutine 中的 panic，避免整个程序退出
+func recoverSession(name string) {
+	if r := recover(); r != nil {
+		zlog.Errorf("[%s Session] %s出错: %v\n%s", pluginName, name, r, debug.Stack())
+	}
+}
+
+// awaitSelection 处理选择会话中的回复，返回要提交的下载参数，会话取消或超时时返回 nil
+func awaitSelection(ctx *zero.Ctx, results []ComicSearchResultItem, first int) []string {
+	stop, end := beginSession(sessionKey{groupID: ctx.Event.GroupID, userID: ctx.Event.UserID})
+	defer end()
+
+	next := zero.NewFutureEvent("message", 999, true, selectionReplyRule, ctx.CheckSession())
+	recv, cancel := next.Repeat()
+	defer cancel()
+
+	var detail *ComicDetail // 为 nil 时处于选择漫画阶段，否则处于选择章节阶段
+	timer := time.NewTimer(cfg.selectionTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-timer.C:
+			ctx.SendChain(message.At(ctx.Event.UserID), message.Text(" 选择已超时，会话结束。"))
+			return nil
+		case reply := <-recv:
+			if !serviceEnabled(ctx) { // 会话期间插件在本群被禁用
+				return nil
+			}
+			text := strings.TrimSpace(reply.Event.Message.ExtractPlainText())
+			if text == cancelKeyword {
+				ctx.SendChain(message.Text("已取消。"))
+				return nil
+			}
+			if detail == nil {
+				n, err := strconv.Atoi(text)
+				if err != nil || n < first || n >= first+len(results) {
+					ctx.SendChain(message.Text(fmt.Sprintf("请回复 %d-%d 之间的一个序号，或回复 %s 退出。", first, first+len(results)-1, cancelKeyword)))
+					continue
+				}
+				footer := fmt.Sprintf("回复章节序号下载 (如 1 3 5、1-5、latest、last:3 或 all)，回复 %s 退出。", cancelKeyword)
+				d, ok := showComicDetail(ctx, results[n-first].ID, footer)
+				if !ok || len(d.Chapters) == 0 {
+					return nil
+				}
+				detail = d
+				timer.Reset(cfg.selectionTimeout)
+				continue
+			}
+
+			// 先检查选择是否有效，有误时可以重新回复；有效时按同样的选择提交下载 (详情已缓存)
+			fields := strings.Fields(text)
+			selection, err := parseChapterSelection(fields)
+			if err == nil {
+				_, err = selection.resolve(detail.Chapters)
+			}
+			if err != nil {
+				ctx.SendChain(message.Text(fmt.Sprintf("%s\n请重新回复，或回复 %s 退出。", sanitizeBlock(err.Error(), 0), cancelKeyword)))
+				continue
+			}
+			return append([]string{detail.ID}, fields...)
+		}
+	}
+}
+
+// confirmKeyword 确认下载的回复
+const confirmKeyword = "确认"
+
+// confirmReplyRule 等待确认时只有 "确认" 或 "取消" 的回复才会被处理
+var confirmReplyRule = zero.RegexRule(`^\s*(` + confirmKeyword + `|` + cancelKeyword + `)\s*$`)
+
+// confirmDownload 发送提示并等待发起者回复 "确认" 或 "取消"
+// 回复取消、超时或被同一用户的新会话取代时返回 false
+func confirmDownload(ctx *zero.Ctx, prompt string) bool {
+	stop, end := beginSession(sessionKey{groupID: ctx.Event.GroupID, userID: ctx.Event.UserID})
+	defer end()
+
+	next := zero.NewFutureEvent("message", 999, true, confirmReplyRule, ctx.CheckSession())
+	recv, cancel := next.Repeat()
+	defer cancel()
+
+	ctx.SendChain(message.Text(fmt.Sprintf("%s\n回复 %s 开始下载，回复 %s 放弃 (%v 内有效)。", prompt, confirmKeyword, cancelKeyword, cfg.selectionTimeout)))
+	timer := time.NewTimer(cfg.selectionTimeout)
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		ctx.SendChain(message.At(ctx.Event.UserID), message.Text(" 确认已超时，下载已取消。"))
+		return false
+	case reply := <-recv:
+		if !serviceEnabled(ctx) {
+			return false
+		}
+		if strings.TrimSpace(reply.Event.Message.ExtractPlainText()) == confirmKeyword {
+			return true
+		}
+		ctx.SendChain(message.Text("已取消。"))
+		return false
+	}
+}
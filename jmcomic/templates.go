@@ -0,0 +1,361 @@
+package jmcomic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	zlog "github.com/FloatTech/zerobot/common/log"
+	"github.com/FloatTech/zerobot/common/message"
+)
+
+// 消息模板名称，覆盖文件为 template_dir 下的 <名称>.tmpl
+const (
+	tmplHelp        = "help"         // jm help
+	tmplCommandHelp = "command_help" // jm help <命令>
+	tmplSearch      = "search"       // 搜索结果列表
+	tmplDetail      = "detail"       // 漫画详情
+	tmplDownload    = "download"     // 下载任务已提交
+	tmplJobDone     = "job_done"     // 下载任务结束的通知
+	tmplJobStatus   = "job_status"   // jm status
+	tmplJobs        = "jobs"         // jm jobs
+	tmplRecognize   = "recognize"    // 自动识别的回复
+	tmplError       = "error"        // 请求失败的提示
+)
+
+// defaultTemplates 内置模板，覆盖文件缺失或无效时使用
+var defaultTemplates = map[string]string{
+	tmplHelp: `{{.Name}} 插件帮助 (JMComic):
+{{range $i, $c := .Commands}}{{add $i 1}}. {{cmd}} {{$c.Usage}} - {{$c.Summary}}{{if $c.Permission}} ({{$c.Permission}}){{end}}
+{{range $c.Notes}}   {{.}}
+{{end}}{{end}}发送 {{cmd}} help <命令> 查看命令的详细用法。`,
+
+	tmplCommandHelp: `{{cmd}} {{.Usage}}
+{{.Summary}}{{if .Aliases}}
+别名: {{join ", " .Aliases}}{{end}}{{if .Permission}}
+权限: {{.Permission}}{{end}}{{range .Notes}}
+{{.}}{{end}}{{if .Details}}
+{{.Details}}{{end}}`,
+
+	tmplSearch: `'{{.Keyword}}' 共 {{.Total}} 个结果，第 {{.Page}}/{{.PageCount}} 页，本页第 {{.First}}-{{.Last}} 条:
+{{range .Items}}{{.Index}}. {{.Title}} (ID: {{.ID}})
+   作者: {{.Author}}
+{{cover .ID}}{{end}}{{if .HasMore}}
+发送 {{cmd}} more 查看更多结果。{{end}}{{if .Interactive}}
+回复序号 ({{.First}}-{{.Last}}) 查看详情，回复 {{.CancelKeyword}} 退出；也可使用 {{cmd}} detail <漫画ID>。{{else}}
+使用 {{cmd}} detail <漫画ID> 查看详情和章节。{{end}}`,
+
+	tmplDetail: `漫画: {{.Title}} (ID: {{.ID}})
+作者: {{.Author}}
+标签: {{.Tags}}
+简介: {{truncate 200 .Description}}
+{{cover .ID}}
+章节列表 (部分):
+{{range $i, $c := limit .MaxChapters .Chapters}}{{add $i 1}}. {{$c.Title}} (章节ID: {{$c.ID}}, 页数: {{$c.PageCount}})
+{{end}}{{if gt (len .Chapters) .MaxChapters}}...等共 {{len .Chapters}} 个章节。
+{{end}}
+{{.Footer}}`,
+
+	tmplDownload: `下载任务已提交: {{.Message}}
+任务ID: {{.JobID}}
+章节: {{range $i, $c := limit 10 .Chapters}}{{if $i}}、{{end}}{{if $c.Title}}{{$c.Title}}{{else}}章节 {{$c.ID}}{{end}}{{end}}
+{{- if gt (len .Chapters) 10}} 等 {{len .Chapters}} 个章节{{end}}
+使用 {{cmd}} status {{.JobID}} 查询进度，完成后会通知您。{{if .Format}}
+下载完成后将打包为 {{upper .Format}} 文件发送。{{end}}`,
+
+	tmplJobDone: `{{if .Failed -}}
+下载任务 {{.JobID}} (漫画 {{.AlbumID}}) 失败: {{.Error}}
+{{- else -}}
+下载任务 {{.JobID}} (漫画 {{.AlbumID}}) 已完成: {{.Status.Message}}
+{{- if .Deliver}}
+文件将通过群文件/私聊文件发送，请稍候。
+{{- else if .Status.DownloadPathHint}}
+提示: 文件可能保存在API服务器的 {{.Status.DownloadPathHint}} 目录中。
+{{- end}}{{end}}`,
+
+	tmplJobStatus: `任务ID: {{.JobID}}
+漫画: {{.AlbumID}}
+章节: {{.ChapterIDs}}
+状态: {{.State}}{{if .Status.Message}}
+信息: {{.Status.Message}}{{end}}{{if .Error}}
+错误: {{.Error}}{{end}}`,
+
+	tmplJobs: `共 {{len .Jobs}} 个下载任务:
+{{range $i, $job := limit .Max .Jobs}}{{add $i 1}}. {{$job.JobID}} [{{$job.State}}] 漫画 {{$job.AlbumID}} ({{$job.SubmittedAt.Format "01-02 15:04"}})
+{{end}}{{if gt (len .Jobs) .Max}}...等共 {{len .Jobs}} 个任务。
+{{end}}
+使用 {{cmd}} status <任务ID> 查看详情。`,
+
+	tmplRecognize: `JM{{.ID}}: {{.Title}}
+作者: {{.Author}}
+标签: {{.Tags}}
+发送 {{cmd}} detail {{.ID}} 查看章节。`,
+
+	tmplError: `{{.Action}}: {{.Message}}`,
+}
+
+// helpView help 模板的数据，Commands 按注册顺序排列
+type helpView struct {
+	Name     string // 插件名称 (大写)
+	Commands []commandHelp
+}
+
+// searchView search 模板的数据，Keyword 已经过清理
+type searchView struct {
+	Keyword       string
+	Total         int
+	Page          int
+	PageCount     int
+	First, Last   int              // 本条消息中结果的序号范围 (从1开始)
+	Items         []searchItemView // 本条消息中的结果
+	HasMore       bool             // 是否可以使用 jm more 查看更多
+	Interactive   bool             // 是否开启了交互式选择
+	CancelKeyword string
+}
+
+// searchItemView 带序号的搜索结果
+type searchItemView struct {
+	Index int
+	ComicSearchResultItem
+}
+
+// detailView detail 模板的数据
+type detailView struct {
+	ComicDetail
+	MaxChapters int    // 最多显示的章节数 (max_chapters_display)
+	Footer      string // 下载用法或交互式选择的提示
+}
+
+// downloadView download 模板的数据
+type downloadView struct {
+	JobID      string
+	AlbumID    string
+	ChapterIDs []string
+	Chapters   []ChapterInfo // 提交的章节，获取不到漫画详情时只有ID
+	Message    string        // API服务返回的提示
+	Format     string        // 打包格式，未指定时为空
+}
+
+// jobView job_done、job_status 和 jobs 模板中的任务
+type jobView struct {
+	DownloadJob
+	State   string // 状态的中文描述
+	Error   string // 失败时的错误提示
+	Failed  bool
+	Deliver bool // 完成后是否会发送文件 (deliver_files)
+}
+
+// jobsView jobs 模板的数据
+type jobsView struct {
+	Jobs []jobView
+	Max  int // 最多显示的任务数
+}
+
+// errorView error 模板的数据
+type errorView struct {
+	Action  string // 失败的操作，例如 "搜索失败"
+	Message string // 错误提示
+}
+
+// templateSamples 加载覆盖模板时用于试运行的示例数据，字段写错的模板会在加载时被发现
+func templateSamples() map[string]interface{} {
+	detail := ComicDetail{
+		ID: "350234", Title: "示例标题", Author: "示例作者", Tags: "标签1, 标签2", Description: "示例简介",
+		Chapters:   []ChapterInfo{{ID: "350235", Title: "第1话", Index: "1", PageCount: 20}},
+		SourceSite: "JMComic",
+	}
+	job := jobView{
+		DownloadJob: DownloadJob{
+			JobID: "0123456789abcdef", AlbumID: detail.ID, ChapterIDs: []string{"350235"},
+			Format: "pdf", SubmittedAt: time.Now(), CheckedAt: time.Now(),
+			Status: JobStatus{JobID: "0123456789abcdef", AlbumID: detail.ID, State: JobStateCompleted, Message: "下载完成", DownloadPathHint: "/data/jm"},
+		},
+		State: jobStateText(JobStateCompleted),
+	}
+	command := commandHelp{
+		Name: "detail", Usage: "detail <漫画ID>", Summary: "获取漫画详情", Aliases: []string{"info"},
+		Permission: permSuperUser.text(), Notes: []string{"示例说明"}, Details: "示例详细说明",
+	}
+	item := ComicSearchResultItem{ID: detail.ID, Title: detail.Title, Author: detail.Author, Tags: detail.Tags}
+	return map[string]interface{}{
+		tmplHelp:        helpView{Name: strings.ToTitle(pluginName), Commands: []commandHelp{command}},
+		tmplCommandHelp: command,
+		tmplSearch: searchView{
+			Keyword: "示例", Total: 1, Page: 1, PageCount: 1, First: 1, Last: 1,
+			Items: []searchItemView{{Index: 1, ComicSearchResultItem: item}}, Interactive: true, CancelKeyword: cancelKeyword,
+		},
+		tmplDetail:    detailView{ComicDetail: detail, MaxChapters: 10, Footer: "示例提示"},
+		tmplDownload:  downloadView{JobID: job.JobID, AlbumID: job.AlbumID, ChapterIDs: job.ChapterIDs, Chapters: detail.Chapters, Message: "已提交", Format: "pdf"},
+		tmplJobDone:   job,
+		tmplJobStatus: job,
+		tmplJobs:      jobsView{Jobs: []jobView{job}, Max: maxJobsDisplay},
+		tmplRecognize: detail,
+		tmplError:     errorView{Action: "搜索失败", Message: "请求超时，请稍后再试"},
+	}
+}
+
+// coverMarker 模板函数 cover 输出的占位符，renderChain 将其替换为封面图
+// 占位符以 \x00 包围，来自API服务和用户的文本都经过清理，不会包含它
+var coverMarker = regexp.MustCompile("\x00cover:([^\x00]*)\x00")
+
+// templateFuncs 模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	// cmd 命令前缀，例如 {{cmd}} detail
+	"cmd": func() string { return cmdPrefix },
+	// truncate 按字符截断，例如 {{truncate 50 .Title}}
+	"truncate": func(n int, s string) string { return truncateRunes(s, n) },
+	// join 连接字符串列表，例如 {{join ", " .ChapterIDs}}
+	"join": func(sep string, items []string) string { return strings.Join(items, sep) },
+	// limit 取列表的前 n 项，例如 {{range limit 5 .Chapters}}
+	"limit": limitList,
+	// add 整数相加，例如 {{add $i 1}}
+	"add":   func(a, b int) int { return a + b },
+	"upper": strings.ToUpper,
+	// cover 在此处插入漫画封面 (开启封面时)，例如 {{cover .ID}}
+	"cover": func(id string) string { return "\x00cover:" + id + "\x00" },
+}
+
+// limitList 返回切片的前 n 项，n 小于0时视为0
+func limitList(n int, list interface{}) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("limit 只能用于列表，得到 %T", list)
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n >= v.Len() {
+		return list, nil
+	}
+	return v.Slice(0, n).Interface(), nil
+}
+
+// builtinTemplates 解析后的内置模板；templates 为当前使用的模板，由 loadTemplates 设置
+var (
+	builtinTemplates = mustParseDefaults()
+	templates        = builtinTemplates
+)
+
+func mustParseDefaults() map[string]*template.Template {
+	parsed := make(map[string]*template.Template, len(defaultTemplates))
+	for name, text := range defaultTemplates {
+		parsed[name] = template.Must(template.New(name).Funcs(templateFuncs).Parse(text))
+	}
+	return parsed
+}
+
+// loadTemplates 读取 dir 中的覆盖模板，无效的模板记录日志后使用内置模板
+// 每个覆盖模板都会用示例数据试运行一次，引用了不存在的字段或函数的模板不会被使用
+func loadTemplates(dir string) map[string]*template.Template {
+	loaded := make(map[string]*template.Template, len(builtinTemplates))
+	for name, tmpl := range builtinTemplates {
+		loaded[name] = tmpl
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			zlog.Warnf("[%s Template] 读取模板目录 %s 失败: %v，使用内置模板", pluginName, dir, err)
+		}
+		return loaded
+	}
+
+	samples := templateSamples()
+	var overridden []string
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || filepath.Ext(fileName) != ".tmpl" {
+			continue
+		}
+		name := strings.TrimSuffix(fileName, ".tmpl")
+		if _, ok := defaultTemplates[name]; !ok {
+			zlog.Warnf("[%s Template] 忽略未知的模板 %s，可用的模板: %s", pluginName, fileName, strings.Join(templateNames(), ", "))
+			continue
+		}
+		tmpl, err := parseTemplateFile(filepath.Join(dir, fileName), name, samples[name])
+		if err != nil {
+			zlog.Errorf("[%s Template] 模板 %s 无效，使用内置模板: %v", pluginName, fileName, err)
+			continue
+		}
+		loaded[name] = tmpl
+		overridden = append(overridden, name)
+	}
+	if len(overridden) > 0 {
+		sort.Strings(overridden)
+		zlog.Infof("[%s Template] 已加载覆盖模板: %s", pluginName, strings.Join(overridden, ", "))
+	}
+	return loaded
+}
+
+// parseTemplateFile 解析一个覆盖模板并用示例数据试运行
+func parseTemplateFile(path, name string, sample interface{}) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("试运行失败: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateNames 返回所有模板名称
+func templateNames() []string {
+	names := make([]string, 0, len(defaultTemplates))
+	for name := range defaultTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// executeTemplate 执行模板，覆盖模板运行出错时记录日志并改用内置模板
+func executeTemplate(name string, data interface{}) string {
+	var b strings.Builder
+	err := templates[name].Execute(&b, data)
+	if err == nil {
+		return b.String()
+	}
+	zlog.Errorf("[%s Template] 模板 %s 执行失败，改用内置模板: %v", pluginName, name, err)
+	b.Reset()
+	if err := builtinTemplates[name].Execute(&b, data); err != nil {
+		zlog.Errorf("[%s Template] 内置模板 %s 执行失败: %v", pluginName, name, err)
+	}
+	return b.String()
+}
+
+// renderText 使用模板生成纯文本消息，模板中的封面占位符会被去掉，结果中的CQ码会被转义
+func renderText(name string, data interface{}) string {
+	return escapeCQ(coverMarker.ReplaceAllString(executeTemplate(name, data), ""))
+}
+
+// renderChain 使用模板生成消息链，{{cover ID}} 的位置替换为 covers 中对应的封面
+// 没有对应封面 (未开启或获取失败) 时占位符直接去掉，文字部分的CQ码会被转义
+func renderChain(name string, data interface{}, covers map[string]message.Segment) message.Chain {
+	text := escapeCQ(executeTemplate(name, data))
+	var chain message.Chain
+	last := 0
+	for _, m := range coverMarker.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > last {
+			chain = chain.Add(message.Text(text[last:m[0]]))
+		}
+		if cover, ok := covers[text[m[2]:m[3]]]; ok {
+			chain = chain.Add(cover)
+		}
+		last = m[1]
+	}
+	if last < len(text) {
+		chain = chain.Add(message.Text(text[last:]))
+	}
+	return chain
+}
@@ -0,0 +1,65 @@
+package jmcomic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func writeTemplateFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// checkBuiltin 检查除 overridden 以外的模板都是内置模板
+func checkBuiltin(t *testing.T, loaded map[string]*template.Template, overridden ...string) {
+	t.Helper()
+	if len(loaded) != len(builtinTemplates) {
+		t.Fatalf("加载了 %d 个模板，期望 %d 个", len(loaded), len(builtinTemplates))
+	}
+	skip := make(map[string]bool)
+	for _, name := range overridden {
+		skip[name] = true
+	}
+	for name, tmpl := range builtinTemplates {
+		if got := loaded[name]; skip[name] == (got == tmpl) {
+			t.Errorf("模板 %s 是否为内置模板: %v，期望 %v", name, got == tmpl, !skip[name])
+		}
+	}
+}
+
+func TestLoadTemplatesFallback(t *testing.T) {
+	t.Run("目录不存在", func(t *testing.T) {
+		checkBuiltin(t, loadTemplates(filepath.Join(t.TempDir(), "missing")))
+	})
+	t.Run("目录是文件", func(t *testing.T) {
+		dir := writeTemplateFiles(t, map[string]string{"file": "x"})
+		checkBuiltin(t, loadTemplates(filepath.Join(dir, "file")))
+	})
+	t.Run("无效的模板", func(t *testing.T) {
+		dir := writeTemplateFiles(t, map[string]string{
+			tmplError + ".tmpl":     "失败了 {{.Action}}: {{.Message}}",
+			tmplHelp + ".tmpl":      "{{.Commands",           // 解析失败
+			tmplRecognize + ".tmpl": "{{.NoSuchField}}",      // 示例数据试运行失败
+			tmplDownload + ".tmpl":  `{{nosuchfunc .JobID}}`, // 未定义的函数
+			"unknown.tmpl":          "未知的模板",
+			tmplSearch + ".txt":     "扩展名不对",
+		})
+		loaded := loadTemplates(dir)
+		checkBuiltin(t, loaded, tmplError)
+
+		saved := templates
+		templates = loaded
+		defer func() { templates = saved }()
+		if got := renderError("搜索失败", nil); got != "失败了 搜索失败: 服务出现错误，请稍后再试" {
+			t.Fatalf("覆盖模板的输出为 %q", got)
+		}
+	})
+}
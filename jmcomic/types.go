@@ -1,47 +1,43 @@
 package jmcomic
 
-import "encoding/json"
+import (
+	"time"
 
-// APIResponse 通用API响应结构
-type APIResponse struct {
-	Status           string          `json:"status"` // "success" or "error"
-	Data             json.RawMessage `json:"data,omitempty"`
-	Message          string          `json:"message,omitempty"`
-	DownloadPathHint string          `json:"download_path_hint,omitempty"`
-}
+	"github.com/FloatTech/ZeroBot-Plugin/plugin/jmcomic/sdk"
+)
 
-// ComicSearchResultItem 搜索结果中的单个漫画项
-type ComicSearchResultItem struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Author      string `json:"author"`
-	Tags        string `json:"tags"`
-	Description string `json:"description"`
-	CoverURL    string `json:"cover_url"`
-	SourceSite  string `json:"source_site"`
-}
+// 插件使用 sdk 中定义的数据类型
+type (
+	ComicSearchResultItem = sdk.ComicSearchResultItem
+	SearchOptions         = sdk.SearchOptions
+	SearchMeta            = sdk.SearchMeta
+	SearchPage            = sdk.SearchPage
+	ChapterInfo           = sdk.ChapterInfo
+	PageImage             = sdk.PageImage
+	PhotoInfo             = sdk.PhotoInfo
+	ComicDetail           = sdk.ComicDetail
+	JobState              = sdk.JobState
+	JobStatus             = sdk.JobStatus
+	JobFile               = sdk.JobFile
+)
 
-// ChapterInfo 漫画章节信息
-type ChapterInfo struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Index     string `json:"index"`
-	PageCount int    `json:"page_count"`
-}
-
-// ComicDetail 漫画详细信息
-type ComicDetail struct {
-	ID          string        `json:"id"`
-	Title       string        `json:"title"`
-	Author      string        `json:"author"`
-	Tags        string        `json:"tags"`
-	Description string        `json:"description"`
-	CoverURL    string        `json:"cover_url"`
-	Chapters    []ChapterInfo `json:"chapters"`
-	SourceSite  string        `json:"source_site"`
-}
+const (
+	JobStateQueued    = sdk.JobStateQueued
+	JobStateRunning   = sdk.JobStateRunning
+	JobStateCompleted = sdk.JobStateCompleted
+	JobStateFailed    = sdk.JobStateFailed
+)
 
-// DownloadRequest 下载请求体
-type DownloadRequest struct {
-	ChapterIDs []string `json:"chapter_ids"`
+// DownloadJob 插件端记录的下载任务，包含发起者信息和最近一次查询到的状态
+type DownloadJob struct {
+	JobID       string
+	Backend     string // 提交任务的API后端，任务只能在该后端上查询
+	AlbumID     string
+	ChapterIDs  []string
+	UserID      int64
+	GroupID     int64  // 私聊时为0
+	Format      string // 打包格式 (pdf/cbz/zip)，为空时逐个发送原始图片
+	SubmittedAt time.Time
+	CheckedAt   time.Time // 最近一次轮询状态的时间
+	Status      JobStatus
 }
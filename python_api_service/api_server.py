@@ -1,8 +1,14 @@
 import os
 import sys
+import json
+import time
+import uuid
 import logging
-from flask import Flask, request, jsonify, abort
-from jmcomic import create_option, JmHtmlClient, JmApiClient, JmImageClient, JmDownloader, JmcomicText
+import shutil
+import threading
+from concurrent.futures import ThreadPoolExecutor
+from flask import Flask, request, jsonify, abort, send_file
+from jmcomic import create_option, JmHtmlClient, JmApiClient, JmImageClient, JmDownloader, JmcomicText, JmMagicConstants
 
 # 将当前脚本所在目录添加到sys.path，以便jmcomic能正确找到配置文件等
 # 如果jm.yaml与api_server.py在同一目录，通常jmcomic可以自动找到
@@ -62,45 +68,139 @@ def get_image_client():
     return JmImageClient(option_copy)
 
 
+# ---------------- 错误码 ----------------
+# 错误响应中的 code 字段供插件端映射为简短的提示，message 只用于日志和排查
+ERROR_NOT_FOUND = 'not_found'
+ERROR_REGION_BLOCKED = 'region_blocked'
+ERROR_TIMEOUT = 'timeout'
+ERROR_BACKEND_UNAVAILABLE = 'backend_unavailable'
+ERROR_INVALID_ID = 'invalid_id'
+ERROR_RATE_LIMITED = 'rate_limited'
+
+def error_response(message, http_status, code=None):
+    body = {"status": "error", "message": message}
+    if code:
+        body["code"] = code
+    return jsonify(body), http_status
+
+def classify_exception(e):
+    """根据异常推断错误码和HTTP状态码，无法识别时返回 (None, 500)"""
+    text = str(e).lower()
+    if isinstance(e, ConnectionRefusedError):
+        return ERROR_BACKEND_UNAVAILABLE, 503
+    if type(e).__name__ == 'MissingAlbumPhotoException' or 'not found' in text or '不存在' in text:
+        return ERROR_NOT_FOUND, 404
+    if 'region' in text or '地区' in text or '地區' in text:
+        return ERROR_REGION_BLOCKED, 403
+    if isinstance(e, TimeoutError) or 'timed out' in text or 'timeout' in text:
+        return ERROR_TIMEOUT, 504
+    if '429' in text or 'too many requests' in text:
+        return ERROR_RATE_LIMITED, 429
+    return None, 500
+
+def exception_response(e):
+    code, http_status = classify_exception(e)
+    return error_response(str(e), http_status, code)
+
 @app.route('/health', methods=['GET'])
 def health_check():
     if GLOBAL_OPTION is None:
-        return jsonify({"status": "error", "message": "JMComic option not initialized"}), 500
+        return error_response("JMComic option not initialized", 503, ERROR_BACKEND_UNAVAILABLE)
     return jsonify({"status": "ok", "message": "API service is running"}), 200
 
+# 搜索参数: 对外使用可读的名称，内部映射为 jmcomic 的常量
+SEARCH_SORT_MAP = {
+    'latest': JmMagicConstants.ORDER_BY_LATEST,
+    'views': JmMagicConstants.ORDER_BY_VIEW,
+    'pictures': JmMagicConstants.ORDER_BY_PICTURE,
+    'likes': JmMagicConstants.ORDER_BY_LIKE,
+}
+SEARCH_TIME_MAP = {
+    'all': JmMagicConstants.TIME_ALL,
+    'today': JmMagicConstants.TIME_TODAY,
+    'week': JmMagicConstants.TIME_WEEK,
+    'month': JmMagicConstants.TIME_MONTH,
+}
+SEARCH_CATEGORY_MAP = {
+    'all': JmMagicConstants.CATEGORY_ALL,
+    'doujin': JmMagicConstants.CATEGORY_DOUJIN,
+    'single': JmMagicConstants.CATEGORY_SINGLE,
+    'short': JmMagicConstants.CATEGORY_SHORT,
+    'another': JmMagicConstants.CATEGORY_ANOTHER,
+    'hanman': JmMagicConstants.CATEGORY_HANMAN,
+    'meiman': JmMagicConstants.CATEGORY_MEIMAN,
+    'cosplay': JmMagicConstants.CATEGORY_DOUJIN_COSPLAY,
+    '3d': JmMagicConstants.CATEGORY_3D,
+}
+SEARCH_MAIN_TAG_MAP = {
+    'site': 0,   # 站内搜索
+    'work': 1,   # 作品
+    'author': 2, # 作者
+    'tag': 3,    # 标签
+    'actor': 4,  # 登场人物
+}
+
+def parse_search_arg(name, mapping, default):
+    value = request.args.get(name, default).lower()
+    if value not in mapping:
+        raise ValueError(f"Invalid '{name}' parameter '{value}', allowed: {', '.join(mapping)}")
+    return mapping[value]
+
 @app.route('/search', methods=['GET'])
 def search_comic_api():
     keywords = request.args.get('keyword')
     client_type = request.args.get('client_type', 'html') # 默认html
     if not keywords:
-        return jsonify({"status": "error", "message": "Missing 'keyword' parameter"}), 400
+        return error_response("Missing 'keyword' parameter", 400)
+    try:
+        page = int(request.args.get('page', 1))
+    except ValueError:
+        return error_response("Invalid 'page' parameter", 400)
+    if page <= 0:
+        return error_response("'page' must be positive", 400)
+    try:
+        order_by = parse_search_arg('sort', SEARCH_SORT_MAP, 'latest')
+        time_range = parse_search_arg('time', SEARCH_TIME_MAP, 'all')
+        category = parse_search_arg('category', SEARCH_CATEGORY_MAP, 'all')
+        main_tag = parse_search_arg('main_tag', SEARCH_MAIN_TAG_MAP, 'site')
+    except ValueError as e:
+        return error_response(str(e), 400)
 
     try:
         client = get_client(client_type)
-        logging.info(f"Searching for: '{keywords}' using {client_type} client")
-        results = list(client.search_album(keywords)) # search_album 返回生成器
+        logging.info(f"Searching for: '{keywords}' (page {page}, order_by {order_by}, time {time_range}, "
+                     f"category {category}, main_tag {main_tag}) using {client_type} client")
+        search_page = client.search(keywords, page, main_tag, order_by, time_range, category, None) # JmSearchPage 对象
         output = []
-        for album_image in results: # AlbumImage 对象
+        for album_id, info in search_page.content: # (漫画ID, 信息字典)
             output.append({
-                'id': album_image.id,
-                'title': JmcomicText.parse_text(album_image.title), # 确保文本可读
-                'author': ", ".join(JmcomicText.parse_list(album_image.author_list)),
-                'tags': ", ".join(JmcomicText.parse_list(album_image.tag_list)),
-                'description': JmcomicText.parse_text(album_image.description) if hasattr(album_image, 'description') else "N/A",
-                'cover_url': album_image.cover_url if hasattr(album_image, 'cover_url') else None,
-                'source_site': album_image.source_site if hasattr(album_image, 'source_site') else "N/A"
+                'id': str(album_id),
+                'title': JmcomicText.parse_text(info.get('name', '')), # 确保文本可读
+                'author': ", ".join(JmcomicText.parse_list(info.get('author', []))),
+                'tags': ", ".join(JmcomicText.parse_list(info.get('tags', []))),
+                'description': JmcomicText.parse_text(info.get('description', '')) if info.get('description') else "N/A",
+                'cover_url': info.get('cover_url'),
+                'source_site': info.get('source_site', "N/A")
             })
-        logging.info(f"Search for '{keywords}' found {len(output)} results.")
-        return jsonify({"status": "success", "data": output})
+        meta = {
+            'page': page,
+            'page_size': len(output),
+            'total': int(getattr(search_page, 'total', len(output)) or 0),
+            'page_count': int(getattr(search_page, 'page_count', page) or page),
+        }
+        logging.info(f"Search for '{keywords}' page {page} found {len(output)} results (total {meta['total']}).")
+        return jsonify({"status": "success", "data": output, "meta": meta})
     except Exception as e:
         logging.error(f"Error during search for '{keywords}': {e}", exc_info=True)
-        return jsonify({"status": "error", "message": str(e)}), 500
+        return exception_response(e)
 
 @app.route('/comic/<album_id>', methods=['GET'])
 def get_comic_detail_api(album_id):
     client_type = request.args.get('client_type', 'html')
     if not album_id:
-        return jsonify({"status": "error", "message": "Missing 'album_id' in path"}), 400
+        return error_response("Missing 'album_id' in path", 400)
+    if not album_id.isdigit():
+        return error_response(f"Invalid album_id '{album_id}'", 400, ERROR_INVALID_ID)
 
     try:
         client = get_client(client_type)
@@ -131,47 +231,424 @@ def get_comic_detail_api(album_id):
         return jsonify({"status": "success", "data": detail_data})
     except Exception as e:
         logging.error(f"Error fetching detail for album_id '{album_id}': {e}", exc_info=True)
-        return jsonify({"status": "error", "message": str(e)}), 500
+        return exception_response(e)
+
+# ---------------- 封面 ----------------
+# 封面由API服务代为下载并缓存在 base_dir/.covers 下，bot所在主机无需能访问JM的图片CDN
+@app.route('/cover/<album_id>', methods=['GET'])
+def get_cover_api(album_id):
+    if GLOBAL_OPTION is None:
+        return error_response("JMComic option not initialized", 503, ERROR_BACKEND_UNAVAILABLE)
+    if not album_id.isdigit():
+        return error_response(f"Invalid album_id '{album_id}'", 400, ERROR_INVALID_ID)
+    try:
+        covers_dir = os.path.join(GLOBAL_OPTION.dir_rule.base_dir, '.covers')
+        os.makedirs(covers_dir, exist_ok=True)
+        save_path = os.path.abspath(os.path.join(covers_dir, f"{album_id}.jpg"))
+        if not os.path.exists(save_path):
+            logging.info(f"Downloading cover for album_id: '{album_id}'")
+            get_image_client().download_album_cover(album_id, save_path)
+        return send_file(save_path, mimetype='image/jpeg')
+    except Exception as e:
+        logging.error(f"Error fetching cover for album_id '{album_id}': {e}", exc_info=True)
+        return exception_response(e)
+
+# ---------------- 章节预览 ----------------
+def get_preview_dir(photo_id):
+    preview_dir = os.path.join(GLOBAL_OPTION.dir_rule.base_dir, '.preview', str(photo_id))
+    os.makedirs(preview_dir, exist_ok=True)
+    return preview_dir
+
+@app.route('/photo/<photo_id>', methods=['GET'])
+def get_photo_info_api(photo_id):
+    # 由章节 (photo) ID 找到所属的漫画 (album)，用于解析用户粘贴的章节链接
+    client_type = request.args.get('client_type', 'html')
+    if not photo_id.isdigit():
+        return error_response(f"Invalid photo_id '{photo_id}'", 400, ERROR_INVALID_ID)
+    try:
+        client = get_client(client_type)
+        logging.info(f"Fetching info for photo_id: '{photo_id}' using {client_type} client")
+        photo_detail = client.get_photo_detail(photo_id)
+        # 单章节漫画的 album_id 与 photo_id 相同
+        album_id = str(getattr(photo_detail, 'album_id', '') or photo_detail.photo_id)
+        photo_data = {
+            'id': str(photo_detail.photo_id),
+            'album_id': album_id,
+            'title': JmcomicText.parse_text(photo_detail.name) if hasattr(photo_detail, 'name') else "",
+            'index': int(getattr(photo_detail, 'album_index', 0) or 0),
+        }
+        return jsonify({"status": "success", "data": photo_data})
+    except Exception as e:
+        logging.error(f"Error fetching info for photo_id '{photo_id}': {e}", exc_info=True)
+        return exception_response(e)
+
+@app.route('/photo/<photo_id>/pages', methods=['GET'])
+def get_photo_pages_api(photo_id):
+    client_type = request.args.get('client_type', 'html')
+    if not photo_id.isdigit():
+        return error_response(f"Invalid photo_id '{photo_id}'", 400, ERROR_INVALID_ID)
+    try:
+        limit = int(request.args.get('limit', 3))
+    except ValueError:
+        return error_response("Invalid 'limit' parameter", 400)
+    if limit <= 0:
+        return error_response("'limit' must be positive", 400)
+
+    try:
+        client = get_client(client_type)
+        logging.info(f"Fetching pages for photo_id: '{photo_id}' (limit {limit}) using {client_type} client")
+        photo_detail = client.get_photo_detail(photo_id)
+        pages_output = []
+        for index in range(min(limit, len(photo_detail))):
+            image = photo_detail.create_photo_image(index)
+            pages_output.append({
+                'index': index,
+                'filename': image.filename_without_suffix + image.img_file_suffix,
+                'url': image.download_url,  # 原始CDN地址，图片可能经过切割打乱，需通过下方接口获取还原后的图片
+            })
+        return jsonify({"status": "success", "data": pages_output})
+    except Exception as e:
+        logging.error(f"Error fetching pages for photo_id '{photo_id}': {e}", exc_info=True)
+        return exception_response(e)
+
+@app.route('/photo/<photo_id>/pages/<int:page_index>', methods=['GET'])
+def get_photo_page_image_api(photo_id, page_index):
+    client_type = request.args.get('client_type', 'html')
+    if not photo_id.isdigit():
+        return error_response(f"Invalid photo_id '{photo_id}'", 400, ERROR_INVALID_ID)
+    try:
+        client = get_client(client_type)
+        photo_detail = client.get_photo_detail(photo_id)
+        if page_index < 0 or page_index >= len(photo_detail):
+            return error_response(f"Page index {page_index} out of range", 404, ERROR_NOT_FOUND)
+        image = photo_detail.create_photo_image(page_index)
+        save_path = os.path.join(get_preview_dir(photo_detail.photo_id), f"{page_index}{image.img_file_suffix}")
+        if not os.path.exists(save_path):
+            # 下载并还原被切割的图片，结果缓存在 base_dir/.preview 下
+            get_image_client().download_by_image_detail(image, save_path)
+        return send_file(os.path.abspath(save_path))
+    except Exception as e:
+        logging.error(f"Error fetching page {page_index} for photo_id '{photo_id}': {e}", exc_info=True)
+        return exception_response(e)
+
+# ---------------- 下载任务 ----------------
+# 下载任务在后台线程中执行，任务状态以JSON文件形式保存在 base_dir/.jobs 下，
+# 这样在 gunicorn 多 worker 部署时，任意 worker 都能查询到同一个任务的状态。
+JOB_STATE_QUEUED = 'queued'
+JOB_STATE_RUNNING = 'running'
+JOB_STATE_COMPLETED = 'completed'
+JOB_STATE_FAILED = 'failed'
+
+_job_lock = threading.Lock()
+
+# 下载在固定大小的线程池中进行，排队的任务数也有上限，避免大量请求耗尽服务器资源
+MAX_DOWNLOAD_WORKERS = int(os.environ.get('JM_DOWNLOAD_WORKERS', 2))
+MAX_PENDING_JOBS = int(os.environ.get('JM_MAX_PENDING_JOBS', 50))
+_download_executor = ThreadPoolExecutor(max_workers=max(1, MAX_DOWNLOAD_WORKERS), thread_name_prefix='jm-download')
+_pending_jobs = 0
+_pending_lock = threading.Lock()
+
+def acquire_pending_slot():
+    global _pending_jobs
+    with _pending_lock:
+        if _pending_jobs >= MAX_PENDING_JOBS:
+            return False
+        _pending_jobs += 1
+        return True
+
+def release_pending_slot():
+    global _pending_jobs
+    with _pending_lock:
+        _pending_jobs -= 1
+
+def run_download_job_slot(job):
+    try:
+        run_download_job(job)
+    finally:
+        release_pending_slot()
+
+# ---------------- 过期文件清理 ----------------
+# 结束的任务记录、预览图片缓存和封面缓存超过保留时间后删除，下载得到的漫画文件不会被删除
+JOB_TTL_SECONDS = int(os.environ.get('JM_JOB_TTL_SECONDS', 7 * 24 * 3600))
+CACHE_TTL_SECONDS = int(os.environ.get('JM_CACHE_TTL_SECONDS', 24 * 3600))
+CLEANUP_INTERVAL_SECONDS = int(os.environ.get('JM_CLEANUP_INTERVAL_SECONDS', 3600))
+
+def cleanup_expired_jobs(now):
+    jobs_dir = get_jobs_dir()
+    removed = 0
+    for name in os.listdir(jobs_dir):
+        if not name.endswith('.json'):
+            continue
+        job = load_job(name[:-len('.json')])
+        if job is None or job.get('state') not in (JOB_STATE_COMPLETED, JOB_STATE_FAILED):
+            continue
+        finished_at = job.get('finished_at') or job.get('updated_at') or 0
+        if now - finished_at < JOB_TTL_SECONDS:
+            continue
+        with _job_lock:
+            try:
+                os.remove(os.path.join(jobs_dir, name))
+                removed += 1
+            except FileNotFoundError:
+                pass
+    return removed
+
+def cleanup_expired_cache(now):
+    base_dir = GLOBAL_OPTION.dir_rule.base_dir
+    removed = 0
+    # .preview 下每个章节一个目录，.covers 下每个漫画一个文件，都按最后修改时间判断
+    for sub in ('.preview', '.covers'):
+        cache_dir = os.path.join(base_dir, sub)
+        if not os.path.isdir(cache_dir):
+            continue
+        for name in os.listdir(cache_dir):
+            path = os.path.join(cache_dir, name)
+            try:
+                if now - os.path.getmtime(path) < CACHE_TTL_SECONDS:
+                    continue
+                if os.path.isdir(path):
+                    shutil.rmtree(path)
+                else:
+                    os.remove(path)
+                removed += 1
+            except FileNotFoundError:
+                pass
+    return removed
+
+def cleanup_loop():
+    while True:
+        time.sleep(CLEANUP_INTERVAL_SECONDS)
+        try:
+            now = time.time()
+            jobs_removed = cleanup_expired_jobs(now)
+            cache_removed = cleanup_expired_cache(now)
+            if jobs_removed or cache_removed:
+                logging.info(f"Cleanup removed {jobs_removed} expired job records and {cache_removed} cached preview/cover entries.")
+        except Exception as e:
+            logging.error(f"Cleanup failed: {e}", exc_info=True)
+
+def get_jobs_dir():
+    jobs_dir = os.path.join(GLOBAL_OPTION.dir_rule.base_dir, '.jobs')
+    os.makedirs(jobs_dir, exist_ok=True)
+    return jobs_dir
+
+def is_valid_job_id(job_id):
+    # job_id 由 uuid4().hex 生成，只允许32位十六进制字符，防止路径穿越
+    return isinstance(job_id, str) and len(job_id) == 32 and all(c in '0123456789abcdef' for c in job_id)
+
+def save_job(job):
+    path = os.path.join(get_jobs_dir(), f"{job['job_id']}.json")
+    tmp_path = path + '.tmp'
+    with _job_lock:
+        with open(tmp_path, 'w', encoding='utf-8') as f:
+            json.dump(job, f, ensure_ascii=False)
+        os.replace(tmp_path, path)
+
+def load_job(job_id):
+    if not is_valid_job_id(job_id):
+        return None
+    path = os.path.join(get_jobs_dir(), f"{job_id}.json")
+    if not os.path.exists(path):
+        return None
+    with _job_lock:
+        with open(path, 'r', encoding='utf-8') as f:
+            return json.load(f)
+
+def update_job(job, **fields):
+    job.update(fields)
+    job['updated_at'] = time.time()
+    save_job(job)
+
+def collect_job_files(album_id, chapter_ids):
+    """收集任务下载得到的图片文件，路径保存为相对 base_dir 的形式，供 /jobs/<job_id>/files 使用"""
+    client = get_client()
+    album_detail = client.get_album_detail(album_id)
+    base_dir = os.path.abspath(GLOBAL_OPTION.dir_rule.base_dir)
+    files = []
+    for chapter_image in album_detail.chapter_list:
+        if str(chapter_image.id) not in [str(c) for c in chapter_ids]:
+            continue
+        photo_detail = client.get_photo_detail(chapter_image.id)
+        image_dir = os.path.abspath(GLOBAL_OPTION.decide_image_save_dir(photo_detail))
+        if not os.path.isdir(image_dir):
+            logging.warning(f"Image directory for chapter '{chapter_image.id}' not found: {image_dir}")
+            continue
+        for name in sorted(os.listdir(image_dir)):
+            full_path = os.path.join(image_dir, name)
+            if not os.path.isfile(full_path):
+                continue
+            files.append({
+                'index': len(files),
+                'chapter_id': str(chapter_image.id),
+                'chapter_index': str(chapter_image.index) if hasattr(chapter_image, 'index') else "N/A",
+                'name': name,
+                'path': os.path.relpath(full_path, base_dir),
+                'size': os.path.getsize(full_path),
+            })
+    return files
+
+def run_download_job(job):
+    album_id = job['album_id']
+    chapter_ids = job['chapter_ids']
+    update_job(job, state=JOB_STATE_RUNNING, message=f"漫画 {album_id} 正在下载中。")
+    try:
+        if not chapter_ids:
+            # 未指定章节时下载全部章节，记录实际的章节ID供查询任务和收集文件使用
+            album_detail = get_client().get_album_detail(album_id)
+            chapter_ids = [str(chapter_image.id) for chapter_image in album_detail.chapter_list]
+            update_job(job, chapter_ids=chapter_ids)
+        # 下载通常使用 JmImageClient
+        # JmImageClient.download_album可以直接处理下载
+        # 它会将文件下载到 GLOBAL_OPTION.dir_rule.base_dir 下的结构化目录中
+        image_client = get_image_client()
+        image_client.download_album(album_id, include_chapters=chapter_ids)
+        files = collect_job_files(album_id, chapter_ids)
+        update_job(job, state=JOB_STATE_COMPLETED, message=f"漫画 {album_id} 的章节 {chapter_ids} 已下载完成。",
+                   files=files, finished_at=time.time())
+        logging.info(f"Download job '{job['job_id']}' for album '{album_id}', chapters {chapter_ids} completed.")
+    except Exception as e:
+        logging.error(f"Download job '{job['job_id']}' for album '{album_id}', chapters {chapter_ids} failed: {e}", exc_info=True)
+        code, _ = classify_exception(e)
+        update_job(job, state=JOB_STATE_FAILED, message=f"漫画 {album_id} 下载失败。", error=str(e),
+                   error_code=code, finished_at=time.time())
 
 @app.route('/download/<album_id>', methods=['POST'])
 def download_chapters_api(album_id):
     if not request.is_json:
-        return jsonify({"status": "error", "message": "Request body must be JSON"}), 400
+        return error_response("Request body must be JSON", 400)
     
     data = request.get_json()
-    chapter_ids = data.get('chapter_ids') # 期望是一个字符串列表
+    chapter_ids = data.get('chapter_ids') # 期望是一个字符串列表，空列表表示下载全部章节
 
-    if not chapter_ids or not isinstance(chapter_ids, list):
-        return jsonify({"status": "error", "message": "Missing or invalid 'chapter_ids' (must be a list of strings)"}), 400
+    if chapter_ids is None or not isinstance(chapter_ids, list):
+        return error_response("Missing or invalid 'chapter_ids' (must be a list of strings)", 400)
+    if not album_id.isdigit():
+        return error_response(f"Invalid album_id '{album_id}'", 400, ERROR_INVALID_ID)
+    if not all(isinstance(c, str) and c.isdigit() for c in chapter_ids):
+        return error_response("Invalid 'chapter_ids' (each must be a numeric string)", 400, ERROR_INVALID_ID)
     
+    if not acquire_pending_slot():
+        return error_response(f"Too many pending download jobs (max {MAX_PENDING_JOBS}), try again later", 429, ERROR_RATE_LIMITED)
+    submitted = False
     try:
-        # 下载通常使用 JmImageClient
-        image_client = get_image_client()
-        logging.info(f"Requesting download for album_id: '{album_id}', chapters: {chapter_ids}")
-        
-        # JmImageClient.download_album可以直接处理下载
-        # 它会将文件下载到 GLOBAL_OPTION.dir_rule.base_dir 下的结构化目录中
-        image_client.download_album(album_id, include_chapters=chapter_ids)
-        
-        # 获取漫画对象以构建下载路径提示 (可选)
-        # album_obj_for_path = image_client.get_album_detail(album_id) # 这会再次请求详情
-        # download_path_hint = GLOBAL_OPTION.dir_rule.comic_image_dir(
-        #     GLOBAL_OPTION.dir_rule.base_dir,
-        #     album_obj_for_path.title # 假设有title属性
-        # )
+        if GLOBAL_OPTION is None:
+            raise ConnectionRefusedError("JMComic global option not initialized. Check jm.yaml.")
+        now = time.time()
         # 简化：提示用户检查API服务器上的配置下载目录
         download_path_hint = f"Check API server's configured download directory: {GLOBAL_OPTION.dir_rule.base_dir}"
+        job = {
+            'job_id': uuid.uuid4().hex,
+            'album_id': album_id,
+            'chapter_ids': chapter_ids,
+            'state': JOB_STATE_QUEUED,
+            'message': f"漫画 {album_id} 的章节 {chapter_ids} 已加入下载队列。" if chapter_ids else f"漫画 {album_id} 的全部章节已加入下载队列。",
+            'error': None,
+            'error_code': None,
+            'download_path_hint': download_path_hint,
+            'created_at': now,
+            'updated_at': now,
+            'finished_at': None,
+            'files': [],
+        }
+        save_job(job)
+        # 立即返回任务ID，下载在后台线程池中进行，避免大本子导致请求超时
+        _download_executor.submit(run_download_job_slot, job)
+        submitted = True
 
-
-        logging.info(f"Download task for album '{album_id}', chapters {chapter_ids} submitted.")
+        logging.info(f"Download job '{job['job_id']}' for album '{album_id}', chapters {chapter_ids} submitted.")
         return jsonify({
             "status": "success",
-            "message": f"漫画 {album_id} 的章节 {chapter_ids} 已提交下载请求。",
+            "message": job['message'],
+            "data": job,
             "download_path_hint": download_path_hint
-        })
+        }), 202
     except Exception as e:
         logging.error(f"Error submitting download for album_id '{album_id}', chapters {chapter_ids}: {e}", exc_info=True)
-        return jsonify({"status": "error", "message": str(e)}), 500
+        return exception_response(e)
+    finally:
+        if not submitted:
+            release_pending_slot()
+
+@app.route('/jobs/<job_id>', methods=['GET'])
+def get_job_status_api(job_id):
+    if GLOBAL_OPTION is None:
+        return error_response("JMComic option not initialized", 503, ERROR_BACKEND_UNAVAILABLE)
+    if not is_valid_job_id(job_id):
+        return error_response(f"Invalid job_id '{job_id}'", 400, ERROR_INVALID_ID)
+    try:
+        job = load_job(job_id)
+        if job is None:
+            return error_response(f"Job '{job_id}' not found", 404, ERROR_NOT_FOUND)
+        data = dict(job, files=job_files_output(job))
+        return jsonify({"status": "success", "data": data, "download_path_hint": job.get('download_path_hint')})
+    except Exception as e:
+        logging.error(f"Error loading job '{job_id}': {e}", exc_info=True)
+        return exception_response(e)
+
+def job_files_output(job):
+    # 不向客户端暴露服务器上的路径
+    return [{k: v for k, v in f.items() if k != 'path'} for f in job.get('files') or []]
+
+@app.route('/jobs/<job_id>/files', methods=['GET'])
+def list_job_files_api(job_id):
+    if GLOBAL_OPTION is None:
+        return error_response("JMComic option not initialized", 503, ERROR_BACKEND_UNAVAILABLE)
+    job = load_job(job_id)
+    if job is None:
+        return error_response(f"Job '{job_id}' not found", 404, ERROR_NOT_FOUND)
+    if job['state'] != JOB_STATE_COMPLETED:
+        return error_response(f"Job '{job_id}' is not completed (state: {job['state']})", 409)
+    return jsonify({"status": "success", "data": job_files_output(job)})
+
+@app.route('/jobs/<job_id>/files/<int:file_index>', methods=['GET'])
+def get_job_file_api(job_id, file_index):
+    if GLOBAL_OPTION is None:
+        return error_response("JMComic option not initialized", 503, ERROR_BACKEND_UNAVAILABLE)
+    job = load_job(job_id)
+    if job is None:
+        return error_response(f"Job '{job_id}' not found", 404, ERROR_NOT_FOUND)
+    files = job.get('files') or []
+    if file_index < 0 or file_index >= len(files):
+        return error_response(f"File index {file_index} out of range", 404, ERROR_NOT_FOUND)
+
+    # 文件只能通过任务记录中的下标访问，路径由服务端记录，再次确认位于 base_dir 内
+    base_dir = os.path.abspath(GLOBAL_OPTION.dir_rule.base_dir)
+    full_path = os.path.abspath(os.path.join(base_dir, files[file_index]['path']))
+    if os.path.commonpath([base_dir, full_path]) != base_dir or not os.path.isfile(full_path):
+        return error_response(f"File index {file_index} is not available", 404, ERROR_NOT_FOUND)
+    return send_file(full_path, as_attachment=True, download_name=files[file_index]['name'])
+
+# ---------------- 启动 ----------------
+def fail_interrupted_jobs():
+    """服务重启后不会再有线程处理之前排队或下载中的任务，将它们标记为失败，避免插件一直等待"""
+    jobs_dir = get_jobs_dir()
+    failed = 0
+    for name in os.listdir(jobs_dir):
+        if not name.endswith('.json'):
+            continue
+        job = load_job(name[:-len('.json')])
+        if job is None or job.get('state') in (JOB_STATE_COMPLETED, JOB_STATE_FAILED):
+            continue
+        update_job(job, state=JOB_STATE_FAILED, message=f"漫画 {job.get('album_id')} 下载失败。",
+                   error="server restarted", error_code=ERROR_BACKEND_UNAVAILABLE, finished_at=time.time())
+        failed += 1
+    return failed
+
+_app_started = False
+
+def create_app():
+    """启动服务时调用一次：处理重启前未完成的任务并启动清理线程。
+    gunicorn 需要加 --preload，使其只在主进程中调用一次"""
+    global _app_started
+    if _app_started or GLOBAL_OPTION is None:
+        return app
+    _app_started = True
+    failed = fail_interrupted_jobs()
+    if failed:
+        logging.warning(f"Marked {failed} interrupted download jobs as failed after server restart.")
+    if CLEANUP_INTERVAL_SECONDS > 0:
+        threading.Thread(target=cleanup_loop, name='jm-cleanup', daemon=True).start()
+    return app
 
 
 if __name__ == '__main__':
@@ -181,10 +658,10 @@ if __name__ == '__main__':
     # debug=True 只用于开发环境，生产环境应使用Gunicorn或Waitress
     # app.run(host=host, port=port, debug=True)
     # 生产环境建议:
-    # Linux/macOS: gunicorn -w 4 -b 0.0.0.0:5000 api_server:app
-    # Windows: waitress-serve --listen=0.0.0.0:5000 api_server:app
+    # Linux/macOS: gunicorn --preload -w 4 -b 0.0.0.0:5000 'api_server:create_app()'
+    # Windows: waitress-serve --listen=0.0.0.0:5000 --call api_server:create_app
     # 这里为了简单，直接运行，但提示用户使用生产级服务器
     logging.info(f"Starting Flask API server on {host}:{port}")
     logging.info("For production, use Gunicorn (Linux/macOS) or Waitress (Windows).")
-    app.run(host=host, port=port, debug=False) # debug=False for default run without Gunicorn
+    create_app().run(host=host, port=port, debug=False) # debug=False for default run without Gunicorn
 
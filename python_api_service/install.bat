@@ -35,7 +35,7 @@ echo.
 echo Setup complete!
 echo To activate the virtual environment manually, run: %VENV_DIR%\Scripts\activate.bat
 echo To run the API server (example for development): python api_server.py
-echo For production, consider using Waitress: waitress-serve --listen=0.0.0.0:5000 api_server:app
+echo For production, consider using Waitress: waitress-serve --listen=0.0.0.0:5000 --call api_server:create_app
 echo Make sure you have configured python_api_service/jm.yaml correctly.
 
 rem Deactivate after script finishes (optional)
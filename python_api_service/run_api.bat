@@ -12,6 +12,6 @@ if not exist "%VENV_DIR%\Scripts\activate.bat" (
 call %VENV_DIR%\Scripts\activate.bat
 
 echo Starting JMComic API server with Waitress on %HOST%:%PORT% with %THREADS% threads...
-waitress-serve --listen=%HOST%:%PORT% --threads=%THREADS% api_server:app
+waitress-serve --listen=%HOST%:%PORT% --threads=%THREADS% --call api_server:create_app
 
 rem To stop, press Ctrl+C in the console where waitress-serve is running.
@@ -15,8 +15,9 @@ source $VENV_DIR/bin/activate
 echo "Starting JMComic API server with Gunicorn on $HOST:$PORT with $WORKERS workers..."
 # Gunicorn 默认会以后台模式运行，除非指定 --daemon
 # 如果要看日志，可以不加 --daemon，或者配置Gunicorn的日志输出
-# gunicorn --workers $WORKERS --bind $HOST:$PORT api_server:app --log-level info
-gunicorn --workers $WORKERS --bind $HOST:$PORT api_server:app
+# gunicorn --preload --workers $WORKERS --bind $HOST:$PORT 'api_server:create_app()' --log-level info
+# --preload 使 create_app 只在主进程中调用一次
+gunicorn --preload --workers $WORKERS --bind $HOST:$PORT 'api_server:create_app()'
 # 要停止，需要找到gunicorn进程并kill
 
 deactivate